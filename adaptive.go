@@ -0,0 +1,106 @@
+package file_streamer
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrInvalidBufferBounds is returned by Streamer.SetAdaptiveBuffers() when bounds make no sense.
+var ErrInvalidBufferBounds = errors.New("invalid adaptive buffer bounds")
+
+// Number of reads in a row that used less than a quarter of buffer, after which the buffer shrinks.
+const adaptiveShrinkAfter = 8
+
+// SetAdaptiveBuffers makes Streamer to size read buffer of each stream between <min> and <max> bytes according to the
+// amount of data appended to the file between reads, instead of using the size of Listener's writer buffer. The buffer
+// grows when a read fills it completely and shrinks after a series of small reads, so large appenders are copied in big
+// pieces while small ones don't waste memory. Memory budget (see SetMemoryBudget) accounts <max> bytes for each stream.
+//
+// Takes effect for streams without encoders that don't use batched reads. Zero <max> (the default) disables
+// adaptive sizing. Returns ErrInvalidBufferBounds when <min> is not positive or is greater than <max>. Can't be
+// changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetAdaptiveBuffers(min, max int) error {
+	if max != 0 && (min <= 0 || min > max) {
+		return ErrInvalidBufferBounds
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.adaptiveMin, s.adaptiveMax = min, max
+	return nil
+}
+
+// adaptiveBuffer is a read buffer resized according to observed sizes of data portions.
+type adaptiveBuffer struct {
+	buf      []byte
+	min, max int
+	small    int // number of reads in a row that used less than a quarter of buffer
+}
+
+func newAdaptiveBuffer(min, max int) *adaptiveBuffer {
+	return &adaptiveBuffer{buf: make([]byte, min), min: min, max: max}
+}
+
+// observe resizes the buffer for the next read after a read of <n> bytes.
+func (b *adaptiveBuffer) observe(n int64) {
+	size := len(b.buf)
+
+	switch {
+	case n >= int64(size) && size < b.max:
+		for size < b.max && int64(size) < n {
+			size *= 2
+		}
+		if size == len(b.buf) {
+			size *= 2 // the burst was as big as the buffer exactly, there may be more next time
+		}
+		if size > b.max {
+			size = b.max
+		}
+		b.small = 0
+
+	case n < int64(size/4) && size > b.min:
+		if b.small++; b.small < adaptiveShrinkAfter {
+			return
+		}
+		if size /= 2; size < b.min {
+			size = b.min
+		}
+		b.small = 0
+
+	default:
+		b.small = 0
+		return
+	}
+
+	if size != len(b.buf) {
+		b.buf = make([]byte, size)
+	}
+}
+
+// copy copies all available data from <src> to <dst>. Each read that fills the buffer completely grows it for the
+// next read, so a big burst is copied in big pieces from the very first time.
+func (b *adaptiveBuffer) copy(dst io.Writer, src io.Reader) error {
+	for {
+		n, err := src.Read(b.buf)
+		if n > 0 {
+			if _, writeErr := dst.Write(b.buf[:n]); writeErr != nil {
+				return writeErr
+			}
+			if n == len(b.buf) {
+				b.observe(int64(n))
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestAdaptiveBufferResize(t *testing.T) {
+	b := newAdaptiveBuffer(1024, 16384)
+
+	b.observe(5000) // a big burst grows the buffer enough to fit it, in power of 2 steps
+	if len(b.buf) != 8192 {
+		t.Fatalf("buffer grew to %d bytes, want 8192", len(b.buf))
+	}
+
+	b.observe(100000) // never beyond the upper bound
+	if len(b.buf) != 16384 {
+		t.Fatalf("buffer grew to %d bytes, want 16384", len(b.buf))
+	}
+
+	for i := 0; i < adaptiveShrinkAfter-1; i++ {
+		b.observe(10)
+	}
+	if len(b.buf) != 16384 {
+		t.Fatalf("buffer shrank to %d bytes after %d small reads", len(b.buf), adaptiveShrinkAfter-1)
+	}
+
+	b.observe(10)
+	if len(b.buf) != 8192 {
+		t.Fatalf("buffer shrank to %d bytes, want 8192", len(b.buf))
+	}
+
+	for i := 0; i < 10*adaptiveShrinkAfter; i++ {
+		b.observe(0)
+	}
+	if len(b.buf) != 1024 {
+		t.Fatalf("buffer shrank to %d bytes, want the lower bound 1024", len(b.buf))
+	}
+}
+
+// countingWriter records sizes of writes.
+type countingWriter struct {
+	bytes.Buffer
+	writes []int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, len(p))
+	return w.Buffer.Write(p)
+}
+
+func TestStreamAdaptiveBuffers(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	if err := s.SetAdaptiveBuffers(0, 1024); err != ErrInvalidBufferBounds {
+		t.Errorf("zero lower bound returned %v, want %v", err, ErrInvalidBufferBounds)
+	}
+	if err := s.SetAdaptiveBuffers(1024, 64*1024); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	data := strings.Repeat("0123456789abcdef", 8*1024) // 128KB
+	out := &countingWriter{}
+	listener := NewListener(openTestFile(t, createTestFile(t, data)), bufio.NewWriterSize(out, 512))
+	catFile(t, s, listener)
+
+	if out.String() != data {
+		t.Fatalf("got %d bytes, want %d", out.Len(), len(data))
+	}
+
+	// the buffer grows while reading the big file: 1+2+4+...+64KB pieces instead of 128 pieces of 1KB
+	if len(out.writes) > 10 {
+		t.Errorf("data was written in %d pieces: %v", len(out.writes), out.writes)
+	}
+}
@@ -0,0 +1,42 @@
+package file_streamer
+
+import "errors"
+
+// ErrEvicted is returned by StreamTo() when the stream was closed by an operator with Streamer.CloseStream().
+var ErrEvicted = errors.New("stream was evicted")
+
+// CloseStream closes the stream of listener with <id> (see Listener.ID() and ActiveStreams()): its StreamTo() returns
+// ErrEvicted. Returns false when there is no active stream with such ID.
+func (s *Streamer) CloseStream(id ListenerID) bool {
+	found := false
+	s.callRouter(func() {
+		for _, listeners := range s.subscriptions {
+			if listener, exists := listeners[id]; exists {
+				listener.closeWithError(ErrEvicted)
+				found = true
+				return
+			}
+		}
+	})
+
+	return found
+}
+
+// CloseStreamsFor closes all streams of the file at <path>, e.g. before the file is deleted or truncated for
+// maintenance. StreamTo() of each closed stream returns ErrEvicted. Returns the number of closed streams.
+//
+// <path> is normalized the same way as names of streamed files are (see SetPathNormalization), so any name of the
+// file works.
+func (s *Streamer) CloseStreamsFor(path string) int {
+	path = s.normalizePath(path)
+
+	closed := 0
+	s.callRouter(func() {
+		for _, listener := range s.subscriptions[path] {
+			listener.closeWithError(ErrEvicted)
+			closed++
+		}
+	})
+
+	return closed
+}
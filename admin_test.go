@@ -0,0 +1,96 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+// startTestStream starts streaming of <name> and waits until the stream is subscribed for file changes.
+func startTestStream(t *testing.T, s *Streamer, name string) (*Listener, chan error) {
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(ioutil.Discard))
+
+	result := make(chan error, 1)
+	go func() { result <- s.StreamTo(listener, 0) }()
+	waitSubscribed(t, s, listener)
+
+	return listener, result
+}
+
+// waitSubscribed waits until Streamer watches the file of <listener>, so no file changes are missed after that.
+func waitSubscribed(t *testing.T, s *Streamer, listener *Listener) {
+	for i := 0; i < 2000; i++ {
+		for _, info := range s.ActiveStreams() {
+			if info.ID == listener.ID() {
+				return
+			}
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("stream of %s was not started", listener.file.Name())
+}
+
+func TestCloseStream(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "data")
+
+	kicked, kickedResult := startTestStream(t, s, name)
+	other, otherResult := startTestStream(t, s, name)
+
+	if !s.CloseStream(kicked.ID()) {
+		t.Fatal("active stream was not found")
+	}
+	if err := <-kickedResult; err != ErrEvicted {
+		t.Errorf("evicted stream ended with %v, want %v", err, ErrEvicted)
+	}
+
+	if s.CloseStream(kicked.ID()) {
+		t.Error("finished stream was closed again")
+	}
+
+	if other.IsClosed() {
+		t.Error("another listener of the same file was closed")
+	}
+	other.Close()
+	if err := <-otherResult; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListenerIDsAreUnique(t *testing.T) {
+	first, second := NewListener(nil, nil), NewListener(nil, nil)
+	if first.ID() == second.ID() {
+		t.Errorf("two listeners got the same ID %d", first.ID())
+	}
+}
+
+func TestCloseStreamsFor(t *testing.T) {
+	s := startTestStreamer(t)
+	name, otherName := createTestFile(t, "data"), createTestFile(t, "data")
+
+	_, first := startTestStream(t, s, name)
+	_, second := startTestStream(t, s, name)
+	other, otherResult := startTestStream(t, s, otherName)
+
+	if closed := s.CloseStreamsFor(name); closed != 2 {
+		t.Errorf("%d streams closed, want 2", closed)
+	}
+
+	for _, result := range []chan error{first, second} {
+		if err := <-result; err != ErrEvicted {
+			t.Errorf("evicted stream ended with %v, want %v", err, ErrEvicted)
+		}
+	}
+
+	if other.IsClosed() {
+		t.Error("stream of another file was closed")
+	}
+	other.Close()
+	<-otherResult
+
+	if closed := s.CloseStreamsFor("/non/existent"); closed != 0 {
+		t.Errorf("%d streams closed for unknown file", closed)
+	}
+}
@@ -0,0 +1,312 @@
+package file_streamer
+
+import (
+	"fmt"
+	"golang.org/x/text/transform"
+	"strconv"
+	"strings"
+)
+
+const (
+	asciiESC = 0x1b
+	asciiBEL = 0x07
+
+	// Escape sequences longer than this are considered broken: we stop waiting for their end and drop the ESC byte.
+	maxEscapeSequenceLen = 256
+)
+
+// Standard xterm palette for 16 base colors: 8 normal (30-37 / 40-47) followed by 8 bright (90-97 / 100-107) ones.
+var ansiPalette = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// ansiStyle is a set of text attributes selected by SGR ('Select Graphic Rendition') sequences.
+type ansiStyle struct {
+	fg, bg    string // CSS colors, empty means default
+	bold      bool
+	faint     bool
+	italic    bool
+	underline bool
+	strike    bool
+}
+
+func (st ansiStyle) css() string {
+	var rules []string
+
+	if st.fg != "" {
+		rules = append(rules, "color:"+st.fg)
+	}
+	if st.bg != "" {
+		rules = append(rules, "background-color:"+st.bg)
+	}
+	if st.bold {
+		rules = append(rules, "font-weight:bold")
+	}
+	if st.faint {
+		rules = append(rules, "opacity:0.7")
+	}
+	if st.italic {
+		rules = append(rules, "font-style:italic")
+	}
+
+	switch {
+	case st.underline && st.strike:
+		rules = append(rules, "text-decoration:underline line-through")
+	case st.underline:
+		rules = append(rules, "text-decoration:underline")
+	case st.strike:
+		rules = append(rules, "text-decoration:line-through")
+	}
+
+	return strings.Join(rules, ";")
+}
+
+// xterm 256 colors: 16 base colors, 6x6x6 color cube and 24 shades of grey.
+func ansi256Color(n int) string {
+	switch {
+	case n < 16:
+		return ansiPalette[n]
+	case n < 232:
+		n -= 16
+		level := func(v int) int {
+			if v == 0 {
+				return 0
+			}
+			return 55 + v*40
+		}
+		return fmt.Sprintf("#%02x%02x%02x", level(n/36), level(n/6%6), level(n%6))
+	default:
+		grey := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", grey, grey, grey)
+	}
+}
+
+// apply returns style modified by SGR parameters.
+func (st ansiStyle) apply(params []int) ansiStyle {
+	if len(params) == 0 {
+		return ansiStyle{} // 'ESC[m' is the same as 'ESC[0m'
+	}
+
+	for i := 0; i < len(params); i++ {
+		switch p := params[i]; {
+		case p == 0:
+			st = ansiStyle{}
+		case p == 1:
+			st.bold = true
+		case p == 2:
+			st.faint = true
+		case p == 3:
+			st.italic = true
+		case p == 4:
+			st.underline = true
+		case p == 9:
+			st.strike = true
+		case p == 22:
+			st.bold, st.faint = false, false
+		case p == 23:
+			st.italic = false
+		case p == 24:
+			st.underline = false
+		case p == 29:
+			st.strike = false
+		case p >= 30 && p <= 37:
+			st.fg = ansiPalette[p-30]
+		case p == 39:
+			st.fg = ""
+		case p >= 40 && p <= 47:
+			st.bg = ansiPalette[p-40]
+		case p == 49:
+			st.bg = ""
+		case p >= 90 && p <= 97:
+			st.fg = ansiPalette[p-90+8]
+		case p >= 100 && p <= 107:
+			st.bg = ansiPalette[p-100+8]
+		case p == 38 || p == 48:
+			var color string
+			switch {
+			case i+2 < len(params) && params[i+1] == 5:
+				color = ansi256Color(params[i+2] & 0xff)
+				i += 2
+			case i+4 < len(params) && params[i+1] == 2:
+				color = fmt.Sprintf("#%02x%02x%02x", params[i+2]&0xff, params[i+3]&0xff, params[i+4]&0xff)
+				i += 4
+			default:
+				return st // broken extended color: ignore the rest of sequence
+			}
+
+			if p == 38 {
+				st.fg = color
+			} else {
+				st.bg = color
+			}
+		}
+	}
+
+	return st
+}
+
+// ansiFilter converts (or strips) ANSI escape sequences in text stream.
+type ansiFilter struct {
+	toHTML bool
+
+	style    ansiStyle
+	spanOpen bool
+}
+
+// NewANSIToHTML returns a transformer that converts ANSI color and text style escape sequences into HTML <span>
+// elements with inline styles. Text itself is HTML-escaped, other escape sequences (cursor movement, terminal titles
+// and so on) are removed.
+//
+// Use it with Listener.AddTransformer() to show colored build and application logs in web UIs.
+func NewANSIToHTML() transform.Transformer {
+	return &ansiFilter{toHTML: true}
+}
+
+// NewANSIStripper returns a transformer that removes all ANSI escape sequences from text stream.
+func NewANSIStripper() transform.Transformer {
+	return &ansiFilter{}
+}
+
+func (f *ansiFilter) Reset() {
+	f.style = ansiStyle{}
+	f.spanOpen = false
+}
+
+// escapeSequenceLen returns length of escape sequence at the beginning of <src>, 0 when sequence is not finished yet.
+func escapeSequenceLen(src []byte) int {
+	if len(src) < 2 {
+		return 0
+	}
+
+	switch src[1] {
+	case '[': // CSI: parameter bytes, intermediate bytes and the final byte
+		for i := 2; i < len(src); i++ {
+			if src[i] >= 0x40 && src[i] <= 0x7e {
+				return i + 1
+			}
+			if src[i] < 0x20 || src[i] > 0x3f {
+				return i // broken sequence, drop everything up to unexpected byte
+			}
+		}
+		return 0
+
+	case ']': // OSC: terminated by BEL or 'ESC \'
+		for i := 2; i < len(src); i++ {
+			if src[i] == asciiBEL {
+				return i + 1
+			}
+			if src[i] == asciiESC && i+1 < len(src) && src[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return 0
+
+	default: // two-byte sequence
+		return 2
+	}
+}
+
+func parseSGRParams(params string) []int {
+	if params == "" {
+		return nil
+	}
+
+	var result []int
+	for _, p := range strings.FieldsFunc(params, func(r rune) bool { return r == ';' || r == ':' }) {
+		value, err := strconv.Atoi(p)
+		if err != nil {
+			value = 0
+		}
+		result = append(result, value)
+	}
+
+	return result
+}
+
+func htmlEscape(c byte) string {
+	switch c {
+	case '&':
+		return "&amp;"
+	case '<':
+		return "&lt;"
+	case '>':
+		return "&gt;"
+	case '"':
+		return "&#34;"
+	case '\'':
+		return "&#39;"
+	}
+
+	return ""
+}
+
+// sgrHTML returns HTML to be written for switching from current style to <style>.
+func (f *ansiFilter) sgrHTML(style ansiStyle) string {
+	var result string
+	if f.spanOpen {
+		result = "</span>"
+	}
+
+	if css := style.css(); css != "" {
+		result += `<span style="` + css + `">`
+	}
+
+	return result
+}
+
+func (f *ansiFilter) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		c := src[nSrc]
+
+		if c != asciiESC {
+			out := ""
+			if f.toHTML {
+				out = htmlEscape(c)
+			}
+
+			if out == "" {
+				if nDst >= len(dst) {
+					return nDst, nSrc, transform.ErrShortDst
+				}
+				dst[nDst] = c
+				nDst++
+			} else {
+				if len(dst)-nDst < len(out) {
+					return nDst, nSrc, transform.ErrShortDst
+				}
+				nDst += copy(dst[nDst:], out)
+			}
+
+			nSrc++
+			continue
+		}
+
+		seqLen := escapeSequenceLen(src[nSrc:])
+		if seqLen == 0 {
+			if !atEOF && len(src)-nSrc < maxEscapeSequenceLen {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+
+			seqLen = 1 // unfinished sequence: drop ESC and treat the rest as a regular text
+		}
+
+		seq := src[nSrc : nSrc+seqLen]
+		if f.toHTML && seqLen > 2 && seq[1] == '[' && seq[seqLen-1] == 'm' {
+			style := f.style.apply(parseSGRParams(string(seq[2 : seqLen-1])))
+			out := f.sgrHTML(style)
+
+			if len(dst)-nDst < len(out) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			nDst += copy(dst[nDst:], out)
+
+			f.style = style
+			f.spanOpen = style.css() != ""
+		}
+
+		nSrc += seqLen
+	}
+
+	return nDst, nSrc, nil
+}
@@ -0,0 +1,55 @@
+package file_streamer
+
+import (
+	"golang.org/x/text/transform"
+	"testing"
+)
+
+func TestANSIToHTML(t *testing.T) {
+	cases := []struct {
+		input, want string
+	}{
+		{"plain <text> & 'quotes'", "plain &lt;text&gt; &amp; &#39;quotes&#39;"},
+		{"\x1b[31mred\x1b[0m", `<span style="color:#cd0000">red</span>`},
+		{"\x1b[1;92mok\x1b[m done", `<span style="color:#00ff00;font-weight:bold">ok</span> done`},
+		{"\x1b[38;5;196mx\x1b[39m", `<span style="color:#ff0000">x</span>`},
+		{"\x1b[48;2;1;2;3my", `<span style="background-color:#010203">y`},
+		{"\x1b[2K\x1b[1Gprogress", "progress"},
+		{"\x1b]0;title\x07text", "text"},
+	}
+
+	for _, c := range cases {
+		got, _, err := transform.String(NewANSIToHTML(), c.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got != c.want {
+			t.Errorf("%q: got %q, want %q", c.input, got, c.want)
+		}
+	}
+}
+
+func TestANSIStripper(t *testing.T) {
+	got, _, err := transform.String(NewANSIStripper(), "\x1b[1;31mERROR\x1b[0m: <failed>\x1b]2;t\x1b\\")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := "ERROR: <failed>"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+// Escape sequences split between file reads have to be recognized as well.
+func TestANSIToHTMLSplitSequence(t *testing.T) {
+	input := []byte("a\x1b[1;31mb\x1b[0mc")
+	want := `a<span style="color:#cd0000;font-weight:bold">b</span>c`
+
+	for i := 0; i <= len(input); i++ {
+		transformer := newChunkTransformer(NewANSIToHTML())
+		if got := transformChunks(t, transformer, input[:i], input[i:]); got != want {
+			t.Errorf("split at %d: got %q, want %q", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package file_streamer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var errNotRegularFile = errors.New("not a regular file")
+
+// ErrPathOutsideRoot is returned for archived files that lead out of Handler root through symlinks.
+var ErrPathOutsideRoot = errors.New("path is outside of root")
+
+// archiveFile is a file snapshot to be put into archive: only info.Size() bytes are archived even if the file grows.
+type archiveFile struct {
+	name string // path inside the archive
+	file *os.File
+	info os.FileInfo
+}
+
+// StreamArchive sends a snapshot of files into zip (ModeZip) or tar.gz (ModeTarGz) archive to <w>, so clients can
+// download full logs next to following them. <files> are put into the archive under their paths relative to <root>.
+// Each file is archived in the size it had when archiving started: data appended later is not included.
+func StreamArchive(w http.ResponseWriter, root string, files []string, mode StreamMode) error {
+	var snapshot []archiveFile
+	defer func() {
+		for _, f := range snapshot {
+			f.file.Close()
+		}
+	}()
+
+	for _, path := range files {
+		file, err := os.Open(path)
+		if err != nil {
+			http.Error(w, "Can't open file for archiving: "+err.Error(), http.StatusNotFound)
+			return err
+		}
+
+		info, err := file.Stat()
+		if err == nil && !info.Mode().IsRegular() {
+			err = errNotRegularFile
+		}
+		if err != nil {
+			file.Close()
+			http.Error(w, "Can't open file for archiving: "+err.Error(), http.StatusNotFound)
+			return err
+		}
+
+		name, err := filepath.Rel(root, path)
+		if err != nil {
+			name = filepath.Base(path)
+		}
+
+		snapshot = append(snapshot, archiveFile{name: filepath.ToSlash(name), file: file, info: info})
+	}
+
+	name := "files"
+	if len(snapshot) == 1 {
+		name = filepath.Base(snapshot[0].name)
+	}
+
+	if mode == ModeZip {
+		w.Header().Set("Content-Type", "application/zip")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.zip"`)
+		return writeZip(w, snapshot)
+	}
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.tar.gz"`)
+	return writeTarGz(w, snapshot)
+}
+
+func writeZip(w io.Writer, files []archiveFile) error {
+	archive := zip.NewWriter(w)
+
+	for _, f := range files {
+		header, err := zip.FileInfoHeader(f.info)
+		if err != nil {
+			return err
+		}
+		header.Name = f.name
+		header.Method = zip.Deflate
+
+		entry, err := archive.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+
+		if _, err = io.Copy(entry, io.LimitReader(f.file, f.info.Size())); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+func writeTarGz(w io.Writer, files []archiveFile) error {
+	compressor := gzip.NewWriter(w)
+	archive := tar.NewWriter(compressor)
+
+	for _, f := range files {
+		header, err := tar.FileInfoHeader(f.info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = f.name
+
+		if err = archive.WriteHeader(header); err != nil {
+			return err
+		}
+
+		n, err := io.Copy(archive, io.LimitReader(f.file, f.info.Size()))
+		if err != nil {
+			return err
+		}
+
+		// the file was truncated meanwhile: tar entry size is already written, pad the entry with zeros
+		if n < f.info.Size() {
+			if _, err = io.CopyN(archive, zeroReader{}, f.info.Size()-n); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	return compressor.Close()
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+// archivedFiles returns files under <root> matched by <pattern>. The path is taken literally when the file exists
+// (names may contain glob meta characters too) or when it has no glob meta characters. Files that lead out of <root>
+// through symlinks are skipped, ErrPathOutsideRoot is returned when the literal path leads out.
+func archivedFiles(root, pattern string) ([]string, error) {
+	canonicalRoot := canonicalPath(root)
+
+	if _, err := os.Lstat(pattern); err == nil || !strings.ContainsAny(pattern, "*?[") {
+		if err == nil && !isInside(canonicalRoot, canonicalPath(pattern)) {
+			return nil, ErrPathOutsideRoot
+		}
+		return []string{pattern}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	files := matches[:0]
+	for _, path := range matches {
+		if !isInside(canonicalRoot, canonicalPath(path)) {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			files = append(files, path)
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,139 @@
+package file_streamer
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func downloadArchive(t *testing.T, url string) []byte {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("got status %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func TestArchiveZip(t *testing.T) {
+	config := DefaultServerConfig()
+	config.Mode = ModeZip
+	server, root := startTestServer(t, config)
+	writeRootFile(t, root, "app.log", "app data")
+	writeRootFile(t, root, "db.log", "db data")
+	writeRootFile(t, root, "notes.txt", "not a log")
+
+	data := downloadArchive(t, server.URL+"/log-stream/*.log")
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := make(map[string]string)
+	for _, f := range archive.File {
+		r, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileData, _ := ioutil.ReadAll(r)
+		r.Close()
+		contents[f.Name] = string(fileData)
+	}
+
+	if len(contents) != 2 || contents["app.log"] != "app data" || contents["db.log"] != "db data" {
+		t.Errorf("unexpected archive contents %v", contents)
+	}
+}
+
+func TestArchiveTarGzDownload(t *testing.T) {
+	server, root := startTestServer(t, DefaultServerConfig())
+	writeRootFile(t, root, "app.log", "app data")
+
+	data := downloadArchive(t, server.URL+"/log-stream/app.log?download=tar.gz")
+	compressed, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	archive := tar.NewReader(compressed)
+	header, err := archive.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileData, _ := ioutil.ReadAll(archive)
+	if header.Name != "app.log" || string(fileData) != "app data" {
+		t.Errorf("unexpected archive entry %q with %q", header.Name, fileData)
+	}
+
+	if _, err = archive.Next(); err != io.EOF {
+		t.Errorf("got %v after the only entry, want EOF", err)
+	}
+
+	for _, path := range []string{"/log-stream/missing.log?download=zip", "/log-stream/app.log?download=rar"} {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("request of %s succeeded", path)
+		}
+	}
+}
+
+func TestArchiveStaysInsideRoot(t *testing.T) {
+	server, root := startTestServer(t, DefaultServerConfig())
+	writeRootFile(t, root, "app[1].log", "literal name")
+
+	secret := createTestFile(t, "SECRET")
+	if err := os.Symlink(secret, filepath.Join(root, "evil")); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := map[string]int{
+		"/log-stream/evil?download=zip":   http.StatusForbidden,
+		"/log-stream/e%2A?download=zip":   http.StatusNotFound,
+		"/log-stream/%2A?download=tar.gz": http.StatusOK,
+	}
+	for path, status := range cases {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != status {
+			t.Errorf("%s: got status %d, want %d", path, resp.StatusCode, status)
+		}
+		if bytes.Contains(body, []byte("SECRET")) {
+			t.Errorf("%s: archive has data of the file outside of root", path)
+		}
+	}
+
+	data := downloadArchive(t, server.URL+"/log-stream/app%5B1%5D.log?download=zip")
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archive.File) != 1 || archive.File[0].Name != "app[1].log" {
+		t.Errorf("file with glob meta characters in its name was not archived literally")
+	}
+}
@@ -0,0 +1,62 @@
+package file_streamer
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Audit event actions
+const (
+	AuditStreamStarted  = "stream_started"
+	AuditStreamFinished = "stream_finished"
+)
+
+// AuditEvent describes who streamed which file, when, and how much data was sent.
+type AuditEvent struct {
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+
+	File       string `json:"file"`
+	RemoteAddr string `json:"remote_addr,omitempty"`
+	Principal  string `json:"principal,omitempty"`
+	Labels     Labels `json:"labels,omitempty"` // see Listener.SetLabels()
+
+	Offset   int64         `json:"offset"`             // position in file the stream started from
+	Bytes    int64         `json:"bytes"`              // amount of file data streamed, set for AuditStreamFinished only
+	Duration time.Duration `json:"duration,omitempty"` // set for AuditStreamFinished only
+	Error    string        `json:"error,omitempty"`    // the reason of stream failure, if any
+}
+
+// AuditSink receives audit events from Streamer.
+//
+// Audit() is called synchronously from StreamTo() and may be called concurrently for different streams, so it should
+// be thread safe and should not block for long.
+type AuditSink interface {
+	Audit(event AuditEvent)
+}
+
+// AuditSinkFunc is an adapter to allow the use of ordinary functions as audit sinks.
+type AuditSinkFunc func(event AuditEvent)
+
+// Audit calls f(event).
+func (f AuditSinkFunc) Audit(event AuditEvent) {
+	f(event)
+}
+
+type jsonAuditSink struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+}
+
+// NewJSONAuditSink creates an audit sink that writes each event to <w> as a single JSON line.
+func NewJSONAuditSink(w io.Writer) AuditSink {
+	return &jsonAuditSink{encoder: json.NewEncoder(w)}
+}
+
+func (s *jsonAuditSink) Audit(event AuditEvent) {
+	s.mu.Lock()
+	_ = s.encoder.Encode(event)
+	s.mu.Unlock()
+}
@@ -0,0 +1,55 @@
+package file_streamer
+
+import (
+	"bufio"
+	"errors"
+	"os"
+)
+
+// ErrBatchedReadsUnsupported is returned by Streamer.SetBatchedReads() on platforms without batched reads backend.
+var ErrBatchedReadsUnsupported = errors.New("batched reads are not supported on this platform")
+
+// newBatchBuffers allocates <count> read buffers of <size> bytes each for one stream.
+func newBatchBuffers(count, size int) [][]byte {
+	bufs := make([][]byte, count)
+	for i := range bufs {
+		bufs[i] = make([]byte, size)
+	}
+
+	return bufs
+}
+
+// copyBatched copies all available data from <src> to <dst>, filling all <bufs> with a single read syscall.
+//
+// Unlike io.Copy() it does not need an extra read to detect the end of data: a batch that was not filled completely
+// means there is no more data in file for now.
+func copyBatched(dst *bufio.Writer, src *os.File, bufs [][]byte) error {
+	batchSize := 0
+	for _, buf := range bufs {
+		batchSize += len(buf)
+	}
+
+	for {
+		n, err := readBatch(src, bufs)
+
+		for i, left := 0, n; left > 0; i++ {
+			chunk := bufs[i]
+			if len(chunk) > left {
+				chunk = chunk[:left]
+			}
+
+			if _, writeErr := dst.Write(chunk); writeErr != nil {
+				return writeErr
+			}
+			left -= len(chunk)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		if n < batchSize {
+			return nil
+		}
+	}
+}
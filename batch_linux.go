@@ -0,0 +1,54 @@
+package file_streamer
+
+import (
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+const batchedReadsSupported = true
+
+// readBatch reads data from <file> into <bufs> with a single readv(2) call.
+func readBatch(file *os.File, bufs [][]byte) (int, error) {
+	rawConn, err := file.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	iovecs := make([]syscall.Iovec, len(bufs))
+	for i, buf := range bufs {
+		iovecs[i].Base = &buf[0]
+		iovecs[i].SetLen(len(buf))
+	}
+
+	var n int
+	var readErr error
+
+	err = rawConn.Read(func(fd uintptr) bool {
+		for {
+			r, _, errno := syscall.Syscall(syscall.SYS_READV, fd, uintptr(unsafe.Pointer(&iovecs[0])), uintptr(len(iovecs)))
+
+			switch errno {
+			case 0:
+				n = int(r)
+				return true
+			case syscall.EINTR:
+				continue
+			case syscall.EAGAIN:
+				// no data in a pipe for now: there is nothing to wait for, the next fs notification will trigger a read
+				return true
+			default:
+				readErr = os.NewSyscallError("readv", errno)
+				return true
+			}
+		}
+	})
+	runtime.KeepAlive(bufs)
+
+	if err != nil {
+		return n, err
+	}
+
+	return n, readErr
+}
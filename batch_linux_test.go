@@ -0,0 +1,54 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestCopyBatched(t *testing.T) {
+	data := strings.Repeat("0123456789abcdef", 1000) // 16000 bytes: 3 full batches of 4x1024 buffers and a short one
+	file := openTestFile(t, createTestFile(t, data))
+
+	var out bytes.Buffer
+	dst := bufio.NewWriterSize(&out, 1024)
+	if err := copyBatched(dst, file, newBatchBuffers(4, 1024)); err != nil {
+		t.Fatal(err)
+	}
+	dst.Flush()
+
+	if out.String() != data {
+		t.Errorf("copied %d bytes, want %d", out.Len(), len(data))
+	}
+}
+
+// Compares regular and batched reads of small appends, which are typical for log files.
+func benchmarkCopy(b *testing.B, batched bool) {
+	file := openTestFile(b, createTestFile(b, strings.Repeat("log line of about sixty four bytes long, with text in it.\n", 64)))
+
+	dst := bufio.NewWriter(ioutil.Discard)
+	buf := make([]byte, 4096)
+	bufs := newBatchBuffers(4, 4096)
+
+	var err error
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		file.Seek(0, io.SeekStart)
+		if batched {
+			err = copyBatched(dst, file, bufs)
+		} else {
+			_, err = io.CopyBuffer(dst, file, buf)
+		}
+		if err != nil {
+			b.Fatal(err)
+		}
+		dst.Flush()
+	}
+}
+
+func BenchmarkCopyRegular(b *testing.B) { benchmarkCopy(b, false) }
+func BenchmarkCopyBatched(b *testing.B) { benchmarkCopy(b, true) }
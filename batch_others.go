@@ -0,0 +1,22 @@
+//go:build !linux
+// +build !linux
+
+package file_streamer
+
+import (
+	"io"
+	"os"
+)
+
+const batchedReadsSupported = false
+
+// readBatch is never called on platforms without batched reads support: Streamer.SetBatchedReads() refuses to
+// enable them. It reads into the first buffer only to keep copyBatched() correct anyway.
+func readBatch(file *os.File, bufs [][]byte) (int, error) {
+	n, err := file.Read(bufs[0])
+	if err == io.EOF {
+		return n, nil
+	}
+
+	return n, err
+}
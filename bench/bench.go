@@ -0,0 +1,284 @@
+// Package bench is a load-test harness for file_streamer.
+//
+// It runs typical streaming scenarios (many listeners of one file, many files, high write rates, slow clients) on
+// real files and reports delivery latency percentiles and allocations, so performance changes of Streamer can be
+// compared before and after.
+package bench
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/badoo/file-streamer"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDeliveryTimeout is returned by Run() when listeners did not receive all written data in time.
+var ErrDeliveryTimeout = errors.New("listeners did not receive all data in time")
+
+// Scenario describes a single load test.
+type Scenario struct {
+	Name string
+
+	Files            int // number of files written simultaneously
+	ListenersPerFile int // number of listeners streaming each file
+
+	Writes        int           // number of writes into each file
+	WriteSize     int           // size of each write, bytes
+	WriteInterval time.Duration // pause between writes into the same file, 0 means 'as fast as possible'
+
+	BufferSize  int           // size of listener's buffered writer, 0 means bufio default
+	ClientDelay time.Duration // time each listener's writer spends on each Write() call (slow client emulation)
+
+	Timeout time.Duration // time given to listeners to receive all data, 0 means 1 minute
+}
+
+// Result is an outcome of Scenario run.
+type Result struct {
+	Scenario Scenario
+
+	Duration       time.Duration // from the first write to the moment all listeners received all data
+	BytesDelivered int64         // amount of data received by all listeners
+
+	Allocs     uint64 // number of heap allocations during the run
+	AllocBytes uint64 // amount of heap memory allocated during the run
+
+	// Delivery latency percentiles: the time between write into file and its receipt by a listener
+	P50, P90, P99, Max time.Duration
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf(
+		"%s: %v, %d bytes delivered, %d allocs (%d bytes), latency p50=%v p90=%v p99=%v max=%v",
+		r.Scenario.Name, r.Duration, r.BytesDelivered, r.Allocs, r.AllocBytes, r.P50, r.P90, r.P99, r.Max,
+	)
+}
+
+// Scenarios returns the standard set of load tests.
+func Scenarios() []Scenario {
+	return []Scenario{
+		{Name: "many-listeners-one-file", Files: 1, ListenersPerFile: 100, Writes: 200, WriteSize: 128, WriteInterval: time.Millisecond},
+		{Name: "one-listener-many-files", Files: 100, ListenersPerFile: 1, Writes: 200, WriteSize: 128, WriteInterval: time.Millisecond},
+		{Name: "high-write-rate", Files: 4, ListenersPerFile: 4, Writes: 10000, WriteSize: 256},
+		{Name: "slow-clients", Files: 4, ListenersPerFile: 8, Writes: 200, WriteSize: 128, WriteInterval: time.Millisecond, ClientDelay: 2 * time.Millisecond},
+	}
+}
+
+// writeTimes remembers when each record was written into a file.
+type writeTimes struct {
+	mu    sync.Mutex
+	times []time.Time
+}
+
+func (w *writeTimes) add(t time.Time) {
+	w.mu.Lock()
+	w.times = append(w.times, t)
+	w.mu.Unlock()
+}
+
+func (w *writeTimes) get(record int) time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.times[record]
+}
+
+// sink is a listener's writer that measures delivery latency of each record.
+type sink struct {
+	recordSize int
+	expected   int64
+	delay      time.Duration
+	written    *writeTimes
+
+	received  int64
+	latencies []time.Duration
+	done      chan struct{}
+}
+
+func (s *sink) Write(p []byte) (int, error) {
+	if s.delay > 0 {
+		time.Sleep(s.delay)
+	}
+
+	now := time.Now()
+	before := s.received
+	s.received += int64(len(p))
+
+	for record := before / int64(s.recordSize); record < s.received/int64(s.recordSize); record++ {
+		s.latencies = append(s.latencies, now.Sub(s.written.get(int(record))))
+	}
+
+	if before < s.expected && s.received >= s.expected {
+		close(s.done)
+	}
+
+	return len(p), nil
+}
+
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	return sorted[int(float64(len(sorted)-1)*p)]
+}
+
+// Run executes the scenario with a new Streamer and returns its result.
+func Run(scenario Scenario) (Result, error) {
+	result := Result{Scenario: scenario}
+
+	if scenario.Files <= 0 || scenario.ListenersPerFile <= 0 || scenario.Writes <= 0 || scenario.WriteSize <= 0 {
+		return result, errors.New("scenario must have positive Files, ListenersPerFile, Writes and WriteSize")
+	}
+
+	timeout := scenario.Timeout
+	if timeout == 0 {
+		timeout = time.Minute
+	}
+
+	dir, err := ioutil.TempDir("", "file-streamer-bench")
+	if err != nil {
+		return result, err
+	}
+	defer os.RemoveAll(dir)
+
+	streamer := file_streamer.New(log.New(ioutil.Discard, "", 0))
+	if err = streamer.Start(); err != nil {
+		return result, err
+	}
+	defer streamer.Stop()
+
+	record := make([]byte, scenario.WriteSize)
+	for i := range record {
+		record[i] = 'x'
+	}
+	record[len(record)-1] = '\n'
+
+	var files []*os.File
+	var sinks []*sink
+	var listeners []*file_streamer.Listener
+	var streams sync.WaitGroup
+
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+		streams.Wait()
+
+		for _, file := range files {
+			file.Close()
+		}
+	}()
+
+	written := make([]*writeTimes, scenario.Files)
+	for i := range written {
+		written[i] = &writeTimes{}
+
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf("file-%d.log", i)))
+		if err != nil {
+			return result, err
+		}
+		files = append(files, file)
+
+		for j := 0; j < scenario.ListenersPerFile; j++ {
+			readFrom, err := os.Open(file.Name())
+			if err != nil {
+				return result, err
+			}
+			files = append(files, readFrom)
+
+			s := &sink{
+				recordSize: scenario.WriteSize,
+				expected:   int64(scenario.Writes * scenario.WriteSize),
+				delay:      scenario.ClientDelay,
+				written:    written[i],
+				done:       make(chan struct{}),
+			}
+			sinks = append(sinks, s)
+
+			writer := bufio.NewWriter(s)
+			if scenario.BufferSize > 0 {
+				writer = bufio.NewWriterSize(s, scenario.BufferSize)
+			}
+
+			listener := file_streamer.NewListener(readFrom, writer)
+			listeners = append(listeners, listener)
+
+			streams.Add(1)
+			go func() {
+				defer streams.Done()
+				_ = streamer.StreamTo(listener, 0)
+			}()
+		}
+	}
+
+	// give streams time to subscribe for file events
+	time.Sleep(100 * time.Millisecond)
+
+	var memBefore, memAfter runtime.MemStats
+	runtime.ReadMemStats(&memBefore)
+	started := time.Now()
+
+	var writers sync.WaitGroup
+	writeErrors := make(chan error, scenario.Files)
+	for i := 0; i < scenario.Files; i++ {
+		writers.Add(1)
+		go func(file *os.File, times *writeTimes) {
+			defer writers.Done()
+
+			for w := 0; w < scenario.Writes; w++ {
+				times.add(time.Now())
+				if _, err := file.Write(record); err != nil {
+					writeErrors <- err
+					return
+				}
+
+				if scenario.WriteInterval > 0 {
+					time.Sleep(scenario.WriteInterval)
+				}
+			}
+		}(files[i*(scenario.ListenersPerFile+1)], written[i])
+	}
+	writers.Wait()
+
+	select {
+	case err = <-writeErrors:
+		return result, err
+	default:
+	}
+
+	deadline := time.After(timeout)
+	for _, s := range sinks {
+		select {
+		case <-s.done:
+		case <-deadline:
+			return result, ErrDeliveryTimeout
+		}
+	}
+
+	result.Duration = time.Since(started)
+	runtime.ReadMemStats(&memAfter)
+	result.Allocs = memAfter.Mallocs - memBefore.Mallocs
+	result.AllocBytes = memAfter.TotalAlloc - memBefore.TotalAlloc
+
+	var latencies []time.Duration
+	for _, s := range sinks {
+		result.BytesDelivered += s.received
+		latencies = append(latencies, s.latencies...)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	result.P50 = percentile(latencies, 0.5)
+	result.P90 = percentile(latencies, 0.9)
+	result.P99 = percentile(latencies, 0.99)
+	result.Max = percentile(latencies, 1)
+
+	return result, nil
+}
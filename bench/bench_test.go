@@ -0,0 +1,39 @@
+package bench
+
+import (
+	"testing"
+	"time"
+)
+
+func runBenchmark(b *testing.B, scenario Scenario) {
+	for i := 0; i < b.N; i++ {
+		result, err := Run(scenario)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.ReportMetric(float64(result.P50)/float64(time.Microsecond), "p50-µs")
+		b.ReportMetric(float64(result.P99)/float64(time.Microsecond), "p99-µs")
+		b.ReportMetric(float64(result.Allocs), "allocs/run")
+		b.ReportMetric(float64(result.BytesDelivered), "bytes/run")
+	}
+}
+
+func BenchmarkScenarios(b *testing.B) {
+	for _, scenario := range Scenarios() {
+		b.Run(scenario.Name, func(b *testing.B) {
+			runBenchmark(b, scenario)
+		})
+	}
+}
+
+func TestRunSmallScenario(t *testing.T) {
+	result, err := Run(Scenario{Name: "small", Files: 2, ListenersPerFile: 2, Writes: 10, WriteSize: 16, Timeout: 10 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := int64(2 * 2 * 10 * 16); result.BytesDelivered != want {
+		t.Errorf("delivered %d bytes, want %d", result.BytesDelivered, want)
+	}
+}
@@ -0,0 +1,188 @@
+package file_streamer
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CheckpointStore records the last streamed offset per (file, consumer) pair, so a restarted server or reconnected
+// consumer resumes streaming exactly where it left off.
+//
+// Load() and Save() are called from StreamTo() and may be called concurrently for different streams, so
+// implementations should be thread safe.
+type CheckpointStore interface {
+	// Load returns saved offset of <file> for <consumer>. <found> is false when there is no checkpoint yet.
+	Load(file, consumer string) (offset int64, found bool, err error)
+
+	// Save records <offset> of <file> for <consumer>.
+	Save(file, consumer string, offset int64) error
+}
+
+type checkpointKey struct {
+	File     string `json:"file"`
+	Consumer string `json:"consumer"`
+}
+
+// MemoryCheckpointStore keeps checkpoints in memory. Use it for reconnecting consumers of a single server process,
+// or in tests.
+type MemoryCheckpointStore struct {
+	mu      sync.Mutex
+	offsets map[checkpointKey]int64
+}
+
+// NewMemoryCheckpointStore creates an empty MemoryCheckpointStore.
+func NewMemoryCheckpointStore() *MemoryCheckpointStore {
+	return &MemoryCheckpointStore{offsets: make(map[checkpointKey]int64)}
+}
+
+func (m *MemoryCheckpointStore) Load(file, consumer string) (int64, bool, error) {
+	m.mu.Lock()
+	offset, found := m.offsets[checkpointKey{file, consumer}]
+	m.mu.Unlock()
+
+	return offset, found, nil
+}
+
+func (m *MemoryCheckpointStore) Save(file, consumer string, offset int64) error {
+	m.mu.Lock()
+	m.offsets[checkpointKey{file, consumer}] = offset
+	m.mu.Unlock()
+
+	return nil
+}
+
+type fileCheckpoint struct {
+	checkpointKey
+	Offset int64 `json:"offset"`
+}
+
+// FileCheckpointStore keeps checkpoints in memory and writes all of them into a JSON file on each Save(). The file is
+// replaced atomically, so it is never left half-written.
+//
+// Streamer saves the checkpoint after each flush of new data, and each Save() with a changed offset rewrites and
+// fsyncs the whole file. That costs a disk sync per flush of every checkpointed stream: use a flush policy (see
+// Listener.SetFlushPolicy()) to flush less often, or implement a CheckpointStore that saves periodically when the
+// rate of flushes is high.
+type FileCheckpointStore struct {
+	path string
+
+	mu      sync.Mutex
+	offsets map[checkpointKey]int64
+}
+
+// NewFileCheckpointStore creates a store backed by the file at <path>, loading checkpoints saved there before. The file
+// is created on the first Save() when it does not exist.
+func NewFileCheckpointStore(path string) (*FileCheckpointStore, error) {
+	store := &FileCheckpointStore{
+		path:    path,
+		offsets: make(map[checkpointKey]int64),
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var checkpoints []fileCheckpoint
+	if err = json.Unmarshal(data, &checkpoints); err != nil {
+		return nil, err
+	}
+
+	for _, checkpoint := range checkpoints {
+		store.offsets[checkpoint.checkpointKey] = checkpoint.Offset
+	}
+
+	return store, nil
+}
+
+func (f *FileCheckpointStore) Load(file, consumer string) (int64, bool, error) {
+	f.mu.Lock()
+	offset, found := f.offsets[checkpointKey{file, consumer}]
+	f.mu.Unlock()
+
+	return offset, found, nil
+}
+
+func (f *FileCheckpointStore) Save(file, consumer string, offset int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := checkpointKey{file, consumer}
+	if saved, found := f.offsets[key]; found && saved == offset {
+		return nil
+	}
+	f.offsets[key] = offset
+
+	checkpoints := make([]fileCheckpoint, 0, len(f.offsets))
+	for key, offset := range f.offsets {
+		checkpoints = append(checkpoints, fileCheckpoint{key, offset})
+	}
+
+	data, err := json.Marshal(checkpoints)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), f.path)
+}
+
+// SetCheckpoint makes Streamer to resume streaming from the offset saved in <store> for <consumer> and to save the
+// offset there after each portion of data is flushed to Listener's writer.
+//
+// Checkpoints are keyed by the normalized file name (see Streamer.SetPathNormalization()), so all names of the file
+// share one checkpoint. The saved offset points right after the data sent to the writer: bytes held by transformers
+// (e.g. an incomplete UTF-8 character) are streamed again after resume.
+//
+// When saved offset is beyond the end of file (the file was truncated or rotated), streaming starts from the beginning
+// of the file. When there is no checkpoint yet, streaming starts from the current position of Listener's file.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetCheckpoint(store CheckpointStore, consumer string) {
+	bs.mu.Lock()
+	bs.checkpoints = store
+	bs.consumer = consumer
+	bs.mu.Unlock()
+}
+
+// resumeFromCheckpoint moves listener's file position to the saved checkpoint.
+func (bs *Listener) resumeFromCheckpoint(store CheckpointStore, consumer string) error {
+	offset, found, err := store.Load(bs.watchPath, consumer)
+	if err != nil || !found {
+		return err
+	}
+
+	info, err := bs.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if offset > info.Size() {
+		offset = 0
+	}
+
+	_, err = bs.file.Seek(offset, io.SeekStart)
+	return err
+}
@@ -0,0 +1,129 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileCheckpointStorePersists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-streamer-checkpoints")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	path := filepath.Join(dir, "checkpoints.json")
+	store, err := NewFileCheckpointStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found, _ := store.Load("/var/log/app.log", "shipper"); found {
+		t.Fatal("empty store has a checkpoint")
+	}
+
+	if err = store.Save("/var/log/app.log", "shipper", 5<<30); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Save("/var/log/app.log", "ui", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewFileCheckpointStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for consumer, want := range map[string]int64{"shipper": 5 << 30, "ui": 10} {
+		offset, found, err := reopened.Load("/var/log/app.log", consumer)
+		if err != nil || !found || offset != want {
+			t.Errorf("%s: loaded %d, %v, %v, want %d", consumer, offset, found, err, want)
+		}
+	}
+}
+
+func TestStreamToResumesFromCheckpoint(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "0123456789")
+
+	store := NewMemoryCheckpointStore()
+	store.Save(canonicalPath(name), "consumer", 4)
+
+	var out bytes.Buffer
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(&out))
+	listener.SetCheckpoint(store, "consumer")
+	catFile(t, s, listener)
+
+	if out.String() != "456789" {
+		t.Errorf("streamed %q, want %q", out.String(), "456789")
+	}
+
+	if offset, _, _ := store.Load(canonicalPath(name), "consumer"); offset != 10 {
+		t.Errorf("checkpoint is %d after streaming, want 10", offset)
+	}
+
+	if _, found, _ := store.Load(canonicalPath(name), "other"); found {
+		t.Error("checkpoint was saved for another consumer")
+	}
+}
+
+func TestStreamToCheckpointBeyondEOF(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "rotated")
+
+	store := NewMemoryCheckpointStore()
+	store.Save(canonicalPath(name), "consumer", 1000)
+
+	var out bytes.Buffer
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(&out))
+	listener.SetCheckpoint(store, "consumer")
+	catFile(t, s, listener)
+
+	if out.String() != "rotated" {
+		t.Errorf("streamed %q, want the whole truncated file", out.String())
+	}
+}
+
+func TestCheckpointSharedByFileNames(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "0123456789")
+
+	link := name + ".link"
+	if err := os.Symlink(name, link); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Remove(link) })
+
+	store := NewMemoryCheckpointStore()
+	store.Save(canonicalPath(name), "consumer", 4)
+
+	var out bytes.Buffer
+	listener := NewListener(openTestFile(t, link), bufio.NewWriter(&out))
+	listener.SetCheckpoint(store, "consumer")
+	catFile(t, s, listener)
+
+	if out.String() != "456789" {
+		t.Errorf("streamed %q through symlink, want %q", out.String(), "456789")
+	}
+}
+
+func TestCheckpointExcludesPendingBytes(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "ab\xd0") // the second byte of the character is not written yet
+
+	store := NewMemoryCheckpointStore()
+
+	var out bytes.Buffer
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(&out))
+	listener.SetEncoder(JSONEncoder)
+	listener.SetCheckpoint(store, "consumer")
+	catFile(t, s, listener)
+
+	if offset, _, _ := store.Load(canonicalPath(name), "consumer"); offset != 2 {
+		t.Errorf("checkpoint is %d, want 2: the incomplete character must be streamed again after resume", offset)
+	}
+}
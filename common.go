@@ -3,13 +3,13 @@ package file_streamer
 import "time"
 
 // Makes 'inifinite' timer when duration is zero.
-func getTimer(duration time.Duration) *time.Timer {
+func getTimer(clock Clock, duration time.Duration) Timer {
 	if duration == 0 {
-		// A hacky way to make timer inifinite. It sends data into .C channel only once when it ticked.
-		t := time.NewTimer(-time.Millisecond)
-		<-t.C
+		// Stopped timer never ticks until it is reset.
+		t := clock.NewTimer(time.Hour)
+		t.Stop()
 		return t
 	}
 
-	return time.NewTimer(duration)
+	return clock.NewTimer(duration)
 }
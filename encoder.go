@@ -0,0 +1,213 @@
+package file_streamer
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"unicode/utf8"
+)
+
+// Encoder converts a chunk of file data into a representation the transport is able to carry (text-only WebSocket
+// channels, JSON-based protocols and so on) before it is written into Listener's buffered writer.
+//
+// Streamer encodes each chunk separately and flushes the buffered writer right after it, so each flush carries
+// self-contained encoded data that can be decoded on its own.
+type Encoder interface {
+	// Encode writes encoded representation of <chunk> into <w>.
+	Encode(w io.Writer, chunk []byte) error
+
+	// ChunkSize returns the maximum size of raw chunk which encoded representation fits into <bufSize> bytes.
+	// Streamer uses it to make sure one encoded chunk does not overflow the buffered writer.
+	ChunkSize(bufSize int) int
+}
+
+var (
+	// IdentityEncoder writes file data as is.
+	IdentityEncoder Encoder = identityEncoder{}
+
+	// Base64Encoder encodes each chunk with standard (padded) base64 encoding.
+	Base64Encoder Encoder = base64Encoder{}
+
+	// HexEncoder encodes each chunk as a lowercase hexadecimal string.
+	HexEncoder Encoder = hexEncoder{}
+
+	// JSONEncoder encodes each chunk as a JSON string followed by a new line symbol.
+	// Multi-byte characters are never split between two chunks, but invalid UTF-8 sequences are replaced with U+FFFD,
+	// so use it only for text files.
+	JSONEncoder Encoder = jsonEncoder{}
+
+	// MsgpackEncoder encodes each chunk as a MessagePack 'bin' object.
+	MsgpackEncoder Encoder = msgpackEncoder{}
+)
+
+// textEncoder is implemented by encoders which are not able to encode a part of multi-byte UTF-8 character.
+// For them Streamer holds the incomplete character at the end of data read from file until the rest of it is written
+// to the file.
+type textEncoder interface {
+	wholeRunes()
+}
+
+// Makes sure we always read at least one byte from file, even when writer's buffer is too small for encoded data.
+func atLeastOne(size int) int {
+	if size < 1 {
+		return 1
+	}
+
+	return size
+}
+
+type identityEncoder struct{}
+
+func (identityEncoder) Encode(w io.Writer, chunk []byte) error {
+	_, err := w.Write(chunk)
+	return err
+}
+
+func (identityEncoder) ChunkSize(bufSize int) int {
+	return atLeastOne(bufSize)
+}
+
+type base64Encoder struct{}
+
+func (base64Encoder) Encode(w io.Writer, chunk []byte) error {
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(chunk)))
+	base64.StdEncoding.Encode(encoded, chunk)
+
+	_, err := w.Write(encoded)
+	return err
+}
+
+func (base64Encoder) ChunkSize(bufSize int) int {
+	return atLeastOne(bufSize / 4 * 3)
+}
+
+type hexEncoder struct{}
+
+func (hexEncoder) Encode(w io.Writer, chunk []byte) error {
+	encoded := make([]byte, hex.EncodedLen(len(chunk)))
+	hex.Encode(encoded, chunk)
+
+	_, err := w.Write(encoded)
+	return err
+}
+
+func (hexEncoder) ChunkSize(bufSize int) int {
+	return atLeastOne(bufSize / 2)
+}
+
+type jsonEncoder struct{}
+
+func (jsonEncoder) Encode(w io.Writer, chunk []byte) error {
+	encoded, err := json.Marshal(string(chunk))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(append(encoded, '\n'))
+	return err
+}
+
+func (jsonEncoder) wholeRunes() {}
+
+// The worst case is '\u00XX' escape sequence for each byte, plus quotes and new line symbol.
+func (jsonEncoder) ChunkSize(bufSize int) int {
+	return atLeastOne((bufSize - 3) / 6)
+}
+
+type msgpackEncoder struct{}
+
+func (msgpackEncoder) Encode(w io.Writer, chunk []byte) error {
+	var header []byte
+
+	switch size := len(chunk); {
+	case size <= 0xff:
+		header = []byte{0xc4, byte(size)}
+	case size <= 0xffff:
+		header = []byte{0xc5, 0, 0}
+		binary.BigEndian.PutUint16(header[1:], uint16(size))
+	default:
+		header = []byte{0xc6, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(header[1:], uint32(size))
+	}
+
+	_, err := w.Write(append(header, chunk...))
+	return err
+}
+
+// 'bin 32' header is the biggest one: 1 byte of type and 4 bytes of data length.
+func (msgpackEncoder) ChunkSize(bufSize int) int {
+	return atLeastOne(bufSize - 5)
+}
+
+// copyChunks reads all available data from <src> chunk by chunk, passes each chunk through <transformer> (when given),
+// encodes the result with <encoder> and flushes it to <dst>.
+func copyChunks(dst *bufio.Writer, src io.Reader, buf []byte, transformer *chunkTransformer, encoder Encoder) error {
+	if encoder == nil {
+		encoder = IdentityEncoder
+	}
+
+	chunkSize := encoder.ChunkSize(len(buf))
+	if chunkSize > len(buf) {
+		chunkSize = len(buf)
+	}
+
+	for {
+		n, err := src.Read(buf[:chunkSize])
+		if n > 0 {
+			data := buf[:n]
+
+			if transformer != nil {
+				var transformErr error
+				data, transformErr = transformer.transform(data)
+				if transformErr != nil {
+					return transformErr
+				}
+			}
+
+			if writeErr := writeEncoded(dst, data, chunkSize, encoder); writeErr != nil {
+				return writeErr
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeEncoded encodes <data> in pieces of at most <chunkSize> bytes and flushes each piece separately.
+// Transformed data may be bigger than the chunk read from file, so we have to split it again. Pieces are cut on UTF-8
+// character boundaries when possible to keep text encoders (like JSONEncoder) from breaking multi-byte characters.
+func writeEncoded(dst *bufio.Writer, data []byte, chunkSize int, encoder Encoder) error {
+	for len(data) > 0 {
+		size := len(data)
+		if size > chunkSize {
+			size = chunkSize
+			for i := 1; i < utf8.UTFMax && size > 1 && !utf8.RuneStart(data[size]); i++ {
+				size--
+			}
+			if !utf8.RuneStart(data[size]) {
+				size = chunkSize
+			}
+		}
+
+		if err := encoder.Encode(dst, data[:size]); err != nil {
+			return err
+		}
+
+		if err := dst.Flush(); err != nil {
+			return err
+		}
+
+		data = data[size:]
+	}
+
+	return nil
+}
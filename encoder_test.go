@@ -0,0 +1,275 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// flushRecorder remembers data of each separate Write() call, which is a single flush of bufio.Writer on top of it.
+type flushRecorder struct {
+	flushes [][]byte
+}
+
+func (r *flushRecorder) Write(p []byte) (int, error) {
+	r.flushes = append(r.flushes, append([]byte(nil), p...))
+	return len(p), nil
+}
+
+func decodeMsgpackBin(t *testing.T, encoded []byte) []byte {
+	var size, headerSize int
+
+	switch encoded[0] {
+	case 0xc4:
+		size, headerSize = int(encoded[1]), 2
+	case 0xc5:
+		size, headerSize = int(binary.BigEndian.Uint16(encoded[1:])), 3
+	case 0xc6:
+		size, headerSize = int(binary.BigEndian.Uint32(encoded[1:])), 5
+	default:
+		t.Fatalf("unexpected msgpack type 0x%x", encoded[0])
+	}
+
+	if len(encoded) != headerSize+size {
+		t.Fatalf("msgpack bin length mismatch: header says %d, got %d bytes of data", size, len(encoded)-headerSize)
+	}
+
+	return encoded[headerSize:]
+}
+
+var encoderDecoders = []struct {
+	name    string
+	encoder Encoder
+	decode  func(t *testing.T, encoded []byte) []byte
+}{
+	{"identity", IdentityEncoder, func(t *testing.T, encoded []byte) []byte { return encoded }},
+	{"base64", Base64Encoder, func(t *testing.T, encoded []byte) []byte {
+		decoded, err := base64.StdEncoding.DecodeString(string(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return decoded
+	}},
+	{"hex", HexEncoder, func(t *testing.T, encoded []byte) []byte {
+		decoded, err := hex.DecodeString(string(encoded))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return decoded
+	}},
+	{"json", JSONEncoder, func(t *testing.T, encoded []byte) []byte {
+		if encoded[len(encoded)-1] != '\n' {
+			t.Fatalf("JSON chunk %q does not end with new line", encoded)
+		}
+
+		var decoded string
+		if err := json.Unmarshal(encoded, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		return []byte(decoded)
+	}},
+	{"msgpack", MsgpackEncoder, decodeMsgpackBin},
+}
+
+func TestEncodersRoundTrip(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain text\n",
+		"<script>alert('&')</script>",
+		"control\x00\x01\x1f chars\t\r\n",
+		"line separators \u2028 \u2029",
+		"мульти-байт 日本語 🙂",
+	}
+
+	for _, ed := range encoderDecoders {
+		for _, input := range inputs {
+			var encoded bytes.Buffer
+			if err := ed.encoder.Encode(&encoded, []byte(input)); err != nil {
+				t.Fatalf("%s: %v", ed.name, err)
+			}
+
+			if decoded := ed.decode(t, encoded.Bytes()); string(decoded) != input {
+				t.Errorf("%s: decoded %q, want %q", ed.name, decoded, input)
+			}
+		}
+	}
+}
+
+func TestEncodersBinaryRoundTrip(t *testing.T) {
+	input := make([]byte, 256)
+	for i := range input {
+		input[i] = byte(i)
+	}
+
+	for _, ed := range encoderDecoders {
+		if ed.encoder == JSONEncoder {
+			continue // JSON can carry text only
+		}
+
+		var encoded bytes.Buffer
+		if err := ed.encoder.Encode(&encoded, input); err != nil {
+			t.Fatalf("%s: %v", ed.name, err)
+		}
+
+		if decoded := ed.decode(t, encoded.Bytes()); !bytes.Equal(decoded, input) {
+			t.Errorf("%s: binary data was not decoded back", ed.name)
+		}
+	}
+}
+
+func TestEncodersChunkSizeWorstCase(t *testing.T) {
+	worstCases := map[string][]byte{
+		"control": bytes.Repeat([]byte{0x01}, 4096),
+		"html":    bytes.Repeat([]byte("<>&"), 4096),
+		"u2028":   bytes.Repeat([]byte("\u2028"), 4096),
+		"invalid": bytes.Repeat([]byte{0xff}, 4096),
+		"binary":  bytes.Repeat([]byte{0xff, 0x00}, 4096),
+	}
+
+	for _, ed := range encoderDecoders {
+		for _, bufSize := range []int{16, 64, 100, 4096} {
+			chunkSize := ed.encoder.ChunkSize(bufSize)
+			if chunkSize < 1 {
+				t.Fatalf("%s: chunk size for %d bytes buffer is %d", ed.name, bufSize, chunkSize)
+			}
+
+			for caseName, data := range worstCases {
+				var encoded bytes.Buffer
+				if err := ed.encoder.Encode(&encoded, data[:chunkSize]); err != nil {
+					t.Fatal(err)
+				}
+
+				if encoded.Len() > bufSize {
+					t.Errorf("%s: %s chunk of %d bytes encoded into %d bytes, buffer size is %d",
+						ed.name, caseName, chunkSize, encoded.Len(), bufSize)
+				}
+			}
+		}
+	}
+}
+
+func TestMsgpackEncoderHeaders(t *testing.T) {
+	cases := []struct {
+		size   int
+		header []byte
+	}{
+		{0, []byte{0xc4, 0x00}},
+		{0xff, []byte{0xc4, 0xff}},
+		{0x100, []byte{0xc5, 0x01, 0x00}},
+		{0xffff, []byte{0xc5, 0xff, 0xff}},
+		{0x10000, []byte{0xc6, 0x00, 0x01, 0x00, 0x00}},
+	}
+
+	for _, c := range cases {
+		var encoded bytes.Buffer
+		if err := MsgpackEncoder.Encode(&encoded, make([]byte, c.size)); err != nil {
+			t.Fatal(err)
+		}
+
+		if !bytes.HasPrefix(encoded.Bytes(), c.header) {
+			t.Errorf("size %d: header % x, want % x", c.size, encoded.Bytes()[:len(c.header)], c.header)
+		}
+
+		if encoded.Len() != len(c.header)+c.size {
+			t.Errorf("size %d: encoded into %d bytes, want %d", c.size, encoded.Len(), len(c.header)+c.size)
+		}
+	}
+}
+
+// Reads are cut in the middle of multi-byte characters: JSON chunks still must contain whole characters only.
+func TestCopyChunksKeepsRunesWhole(t *testing.T) {
+	input := strings.Repeat("日", 20) // 60 bytes
+
+	recorder := &flushRecorder{}
+	dst := bufio.NewWriterSize(recorder, 64)
+	transformer := newChunkTransformer(wholeRunes{})
+
+	err := copyChunks(dst, strings.NewReader(input), make([]byte, 64), transformer, JSONEncoder)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded string
+	for _, flush := range recorder.flushes {
+		var chunk string
+		if err := json.Unmarshal(flush, &chunk); err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.ContainsRune(chunk, utf8.RuneError) {
+			t.Errorf("chunk %q contains broken characters", chunk)
+		}
+
+		if len(flush) > 64 {
+			t.Errorf("chunk of %d bytes overflows 64 bytes writer", len(flush))
+		}
+
+		decoded += chunk
+	}
+
+	if decoded != input {
+		t.Errorf("decoded %q, want %q", decoded, input)
+	}
+}
+
+// Streamer holds the incomplete character until it is completed by the next write to the file.
+func TestCopyChunksHoldsIncompleteRune(t *testing.T) {
+	input := []byte("ab日")
+
+	recorder := &flushRecorder{}
+	dst := bufio.NewWriterSize(recorder, 64)
+	transformer := newChunkTransformer(wholeRunes{})
+	buf := make([]byte, 64)
+
+	if err := copyChunks(dst, bytes.NewReader(input[:3]), buf, transformer, JSONEncoder); err != nil {
+		t.Fatal(err)
+	}
+	if err := copyChunks(dst, bytes.NewReader(input[3:]), buf, transformer, JSONEncoder); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"\"ab\"\n", "\"日\"\n"}
+	if len(recorder.flushes) != len(want) {
+		t.Fatalf("got %d chunks %q, want %q", len(recorder.flushes), recorder.flushes, want)
+	}
+
+	for i := range want {
+		if string(recorder.flushes[i]) != want[i] {
+			t.Errorf("chunk %d is %q, want %q", i, recorder.flushes[i], want[i])
+		}
+	}
+}
+
+func TestWriteEncodedSplitsOnRuneBoundary(t *testing.T) {
+	data := []byte("a" + strings.Repeat("é", 10)) // 1 + 20 bytes, every odd offset is in the middle of 'é'
+
+	recorder := &flushRecorder{}
+	dst := bufio.NewWriterSize(recorder, 64)
+
+	if err := writeEncoded(dst, data, 4, IdentityEncoder); err != nil {
+		t.Fatal(err)
+	}
+
+	var joined []byte
+	for _, flush := range recorder.flushes {
+		if !utf8.Valid(flush) {
+			t.Errorf("chunk %q is cut in the middle of a character", flush)
+		}
+
+		if len(flush) > 4 {
+			t.Errorf("chunk %q is longer than 4 bytes", flush)
+		}
+
+		joined = append(joined, flush...)
+	}
+
+	if !bytes.Equal(joined, data) {
+		t.Errorf("joined chunks %q, want %q", joined, data)
+	}
+}
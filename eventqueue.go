@@ -0,0 +1,138 @@
+package file_streamer
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"sync/atomic"
+)
+
+// defaultEventQueueCapacity is the default capacity of the queue of file system events.
+const defaultEventQueueCapacity = 1000
+
+// EventQueuePolicy defines what Streamer does with a file system event when its queue is full, which happens when
+// files change faster than Streamer notifies their listeners.
+type EventQueuePolicy uint8
+
+const (
+	// EventQueueBlock makes the watcher wait for free space in the queue, delaying all the following events. This is
+	// the default policy.
+	EventQueueBlock EventQueuePolicy = iota
+
+	// EventQueueCoalesce drops write events of files that have an event in the queue already: listeners read all new
+	// data of the file on the queued event anyway. Other events wait for free space.
+	EventQueueCoalesce
+
+	// EventQueueDrop drops the event, so listeners of the file miss a notification: they get new data on the next
+	// change of the file (or the next file check, see Streamer.SetFileCheckInterval()).
+	EventQueueDrop
+)
+
+// EventQueueOptions configures the queue of file system events between the watcher and Streamer's listeners
+// notifications, see Streamer.SetEventQueue().
+type EventQueueOptions struct {
+	Capacity int // 1000 by default
+	Policy   EventQueuePolicy
+
+	// HighWaterMark is the queue length that triggers saturation alert: a log message and OnHighWater call. The alert
+	// is triggered again after the queue length drops below half of the mark. Zero means 3/4 of Capacity.
+	HighWaterMark int
+
+	// OnHighWater is called by the watcher goroutine, so it should return quickly. Nil means 'log only'.
+	OnHighWater func(length, capacity int)
+}
+
+func (o EventQueueOptions) withDefaults() EventQueueOptions {
+	if o.Capacity <= 0 {
+		o.Capacity = defaultEventQueueCapacity
+	}
+	if o.HighWaterMark <= 0 || o.HighWaterMark > o.Capacity {
+		o.HighWaterMark = o.Capacity * 3 / 4
+	}
+
+	return o
+}
+
+// SetEventQueue configures the queue of file system events: its capacity, what happens to events when it is full and
+// when its saturation is alerted. Saturation is visible in Metrics() too.
+//
+// Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetEventQueue(options EventQueueOptions) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.eventQueue = options.withDefaults()
+	return nil
+}
+
+// queueEvent puts <event> into the queue of eventsRouter according to the queue policy, called by sendChangeEvents
+// only.
+func (s *Streamer) queueEvent(event fsnotify.Event) {
+	if len(s.changedFiles) == cap(s.changedFiles) {
+		atomic.AddUint64(&s.metrics.EventQueueOverflows, 1)
+
+		switch s.eventQueue.Policy {
+		case EventQueueDrop:
+			atomic.AddUint64(&s.metrics.DroppedEvents, 1)
+			return
+		case EventQueueCoalesce:
+			if event.Op&(fsnotify.Remove|fsnotify.Rename) == 0 && s.isEventQueued(event.Name) {
+				atomic.AddUint64(&s.metrics.DroppedEvents, 1)
+				return
+			}
+		}
+	}
+
+	if s.eventQueue.Policy == EventQueueCoalesce {
+		s.queuedMu.Lock()
+		s.queuedEvents[event.Name]++
+		s.queuedMu.Unlock()
+	}
+
+	s.changedFiles <- event
+	s.checkEventQueueLength(len(s.changedFiles))
+}
+
+func (s *Streamer) isEventQueued(name string) bool {
+	s.queuedMu.Lock()
+	defer s.queuedMu.Unlock()
+
+	return s.queuedEvents[name] > 0
+}
+
+// dequeueEvent accounts <event> taken from the queue by eventsRouter.
+func (s *Streamer) dequeueEvent(event fsnotify.Event) {
+	if s.eventQueue.Policy != EventQueueCoalesce {
+		return
+	}
+
+	s.queuedMu.Lock()
+	if s.queuedEvents[event.Name]--; s.queuedEvents[event.Name] <= 0 {
+		delete(s.queuedEvents, event.Name)
+	}
+	s.queuedMu.Unlock()
+}
+
+// checkEventQueueLength updates queue peak length and alerts saturation when the queue of <length> events reaches
+// the high-water mark.
+func (s *Streamer) checkEventQueueLength(length int) {
+	if uint64(length) > atomic.LoadUint64(&s.metrics.EventQueuePeak) {
+		atomic.StoreUint64(&s.metrics.EventQueuePeak, uint64(length)) // sendChangeEvents is the only writer
+	}
+
+	mark := s.eventQueue.HighWaterMark
+	switch {
+	case !s.queueSaturated && length >= mark:
+		s.queueSaturated = true
+		atomic.AddUint64(&s.metrics.EventQueueAlerts, 1)
+
+		s.logger.Printf("File events queue is saturated: %d of %d events are waiting", length, s.eventQueue.Capacity)
+		if s.eventQueue.OnHighWater != nil {
+			s.eventQueue.OnHighWater(length, s.eventQueue.Capacity)
+		}
+	case s.queueSaturated && length < mark/2:
+		s.queueSaturated = false
+	}
+}
@@ -0,0 +1,86 @@
+package file_streamer
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+// newQueueStreamer creates Streamer with events queue of <options>, which is not drained by anybody.
+func newQueueStreamer(options EventQueueOptions) *Streamer {
+	s := New(log.New(ioutil.Discard, "", 0))
+	s.eventQueue = options.withDefaults()
+	s.changedFiles = make(chan fsnotify.Event, s.eventQueue.Capacity)
+	s.queuedEvents = make(map[string]int)
+
+	return s
+}
+
+func TestEventQueueDrop(t *testing.T) {
+	s := newQueueStreamer(EventQueueOptions{Capacity: 2, Policy: EventQueueDrop})
+
+	for i := 0; i < 3; i++ {
+		s.queueEvent(fsnotify.Event{Name: "app.log", Op: fsnotify.Write})
+	}
+
+	metrics := s.Metrics()
+	if len(s.changedFiles) != 2 || metrics.DroppedEvents != 1 || metrics.EventQueuePeak != 2 {
+		t.Errorf("%d events are queued with metrics %+v, want 2 queued and 1 dropped", len(s.changedFiles), metrics)
+	}
+}
+
+func TestEventQueueCoalesce(t *testing.T) {
+	s := newQueueStreamer(EventQueueOptions{Capacity: 2, Policy: EventQueueCoalesce})
+
+	s.queueEvent(fsnotify.Event{Name: "app.log", Op: fsnotify.Write})
+	s.queueEvent(fsnotify.Event{Name: "other.log", Op: fsnotify.Write})
+	s.queueEvent(fsnotify.Event{Name: "app.log", Op: fsnotify.Write}) // coalesced with the queued one
+
+	if dropped := s.Metrics().DroppedEvents; dropped != 1 {
+		t.Errorf("%d events are dropped, want 1", dropped)
+	}
+
+	// removal is never coalesced: it waits for free space
+	done := make(chan empty)
+	go func() {
+		s.queueEvent(fsnotify.Event{Name: "app.log", Op: fsnotify.Remove})
+		close(done)
+	}()
+
+	s.dequeueEvent(<-s.changedFiles)
+	<-done
+
+	if s.queuedEvents["app.log"] != 1 || s.queuedEvents["other.log"] != 1 {
+		t.Errorf("queued events are %v, want one event of each file", s.queuedEvents)
+	}
+}
+
+func TestEventQueueHighWater(t *testing.T) {
+	var alerts []int
+	s := newQueueStreamer(EventQueueOptions{
+		Capacity:      10,
+		HighWaterMark: 4,
+		OnHighWater:   func(length, capacity int) { alerts = append(alerts, length) },
+	})
+
+	for i := 0; i < 6; i++ {
+		s.queueEvent(fsnotify.Event{Name: "app.log", Op: fsnotify.Write})
+	}
+	if len(alerts) != 1 || alerts[0] != 4 {
+		t.Fatalf("alerts are %v, want a single alert at 4 events", alerts)
+	}
+
+	// the alert is re-armed when the queue drops below half of the mark
+	for len(s.changedFiles) > 1 {
+		<-s.changedFiles
+	}
+	s.checkEventQueueLength(len(s.changedFiles))
+	for i := 0; i < 3; i++ {
+		s.queueEvent(fsnotify.Event{Name: "app.log", Op: fsnotify.Write})
+	}
+
+	if len(alerts) != 2 || s.Metrics().EventQueueAlerts != 2 {
+		t.Errorf("alerts are %v, want the second alert", alerts)
+	}
+}
@@ -0,0 +1,224 @@
+package file_streamer
+
+import (
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// StreamEventType is the kind of status event Streamer reports alongside file data.
+type StreamEventType uint8
+
+const (
+	// EventFileRotated means a new file appeared at the path of streamed file (e.g. after log rotation). The stream
+	// continues to read the old file until it is removed.
+	EventFileRotated StreamEventType = iota + 1
+
+	// EventFileTruncated means the file became shorter than the stream position. No data is streamed until the file
+	// grows beyond the position again; use Listener.SeekTo() to restart the stream from the beginning.
+	EventFileTruncated
+
+	// EventFileDeleted means the file was removed, the stream stops right after this event.
+	EventFileDeleted
+
+	// EventWatchLost means Streamer could not watch the file changes. StreamEvent.Err holds the reason.
+	EventWatchLost
+
+	// EventHeartbeat is sent periodically when heartbeat is enabled with Listener.SetHeartbeat().
+	EventHeartbeat
+
+	// EventStreamStarted is sent when Streamer starts streaming the file, Offset is the starting position.
+	EventStreamStarted
+
+	// EventStreamStopped is the last event of the stream. StreamEvent.Err holds the error returned by StreamTo().
+	EventStreamStopped
+
+	// EventError reports file read error, the stream stops right after it.
+	EventError
+
+	// EventPermissionDenied reports file read failed with permission error. The stream stops after it, unless
+	// Listener's RetryPolicy allows to retry the read.
+	EventPermissionDenied
+)
+
+func (t StreamEventType) String() string {
+	switch t {
+	case EventFileRotated:
+		return "file_rotated"
+	case EventFileTruncated:
+		return "file_truncated"
+	case EventFileDeleted:
+		return "file_deleted"
+	case EventWatchLost:
+		return "watch_lost"
+	case EventHeartbeat:
+		return "heartbeat"
+	case EventStreamStarted:
+		return "stream_started"
+	case EventStreamStopped:
+		return "stream_stopped"
+	case EventError:
+		return "error"
+	case EventPermissionDenied:
+		return "permission_denied"
+	}
+
+	return "unknown"
+}
+
+// StreamEvent is a status event of the stream, sent to Listener's event handler apart from file data.
+type StreamEvent struct {
+	Type   StreamEventType
+	File   string
+	Offset int64 // stream position in the file when the event happened
+	Time   time.Time
+	Err    error
+}
+
+// EventHandler receives stream status events. It is called from Streamer goroutines and must not block.
+type EventHandler func(event StreamEvent)
+
+// SetEventHandler makes Streamer to report stream status events (file rotation, truncation, removal and so on) to
+// <handler>, so clients can show "log was rotated" instead of silently stopping. Nil disables events.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetEventHandler(handler EventHandler) {
+	bs.mu.Lock()
+	bs.eventHandler = handler
+	bs.mu.Unlock()
+}
+
+// SetHeartbeat makes Streamer to send EventHeartbeat to listener's event handler each <interval>, so clients can tell
+// a quiet file from a broken connection. Zero disables heartbeats.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetHeartbeat(interval time.Duration) {
+	bs.mu.Lock()
+	bs.heartbeat = interval
+	bs.mu.Unlock()
+}
+
+// Events returns the channel of stream status events, an alternative to SetEventHandler(). The channel is closed right after
+// EventStreamStopped event.
+//
+// When events are consumed (by the channel or the handler), file read errors are reported with EventError only, so
+// the data writer receives file bytes and nothing else. Otherwise error message is written into the data stream.
+//
+// Events are dropped when the channel is full, reading it is optional. Should be called before passing Listener to
+// Streamer.StreamTo().
+func (bs *Listener) Events() <-chan StreamEvent {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.events == nil {
+		bs.events = make(chan StreamEvent, eventsQueueSize)
+	}
+
+	return bs.events
+}
+
+// Size of Listener.Events() queue.
+const eventsQueueSize = 100
+
+// hasEventsConsumer reports whether anybody receives listener's status events.
+func (bs *Listener) hasEventsConsumer() bool {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	return bs.eventHandler != nil || bs.events != nil
+}
+
+// closeEvents closes Events() channel. No events are sent after that.
+func (bs *Listener) closeEvents() {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.events != nil && !bs.eventsClosed {
+		close(bs.events)
+	}
+	bs.eventsClosed = true
+}
+
+// emitEvent sends event of <eventType> to listener's event handler and Events() channel, if there are ones.
+func (s *Streamer) emitEvent(listener *Listener, eventType StreamEventType, offset int64, err error) {
+	event := StreamEvent{
+		Type:   eventType,
+		File:   listener.file.Name(),
+		Offset: offset,
+		Time:   s.clock.Now(),
+		Err:    err,
+	}
+
+	listener.mu.Lock()
+	handler := listener.eventHandler
+	if listener.events != nil && !listener.eventsClosed {
+		// the channel is closed under the same lock, so it is safe to send there
+		select {
+		case listener.events <- event:
+		default:
+		}
+	}
+	closed := listener.eventsClosed
+	listener.mu.Unlock()
+
+	if handler != nil && !closed {
+		handler(event)
+	}
+}
+
+// fileState tracks changes of streamed file between reads to report them as events.
+type fileState struct {
+	opened    os.FileInfo // the file being streamed, nil when it could not be stat'ed
+	rotated   bool
+	truncated bool
+}
+
+func newFileState(file *os.File) *fileState {
+	info, _ := file.Stat()
+	return &fileState{opened: info}
+}
+
+// checkFileState compares file state with the previous one and reports changes to listener's event handler. Returns false when
+// file was removed.
+func (s *Streamer) checkFileState(listener *Listener, state *fileState, offset int64) bool {
+	atomic.AddUint64(&s.metrics.FileChecks, 1)
+
+	current, err := os.Stat(listener.file.Name())
+	if err != nil {
+		s.emitEvent(listener, EventFileDeleted, offset, nil)
+		return false
+	}
+
+	if !state.rotated && state.opened != nil && !os.SameFile(state.opened, current) {
+		state.rotated = true
+		s.emitEvent(listener, EventFileRotated, offset, nil)
+	}
+
+	if info, err := listener.file.Stat(); err == nil {
+		truncated := info.Size() < offset
+		if truncated && !state.truncated {
+			s.emitEvent(listener, EventFileTruncated, offset, nil)
+		}
+		state.truncated = truncated
+	}
+
+	return true
+}
+
+// SetFileCheckInterval makes Streamer to check streamed files existence each <interval>. Streamer stats a file when
+// fsnotify reports its removal or rename, and when a notification brings no new data (e.g. after truncation), but
+// removal of a file, which is still open by Streamer, produces no events: without periodic checks such streams stop
+// only by timeout. Zero <interval> (the default) disables periodic checks.
+//
+// Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetFileCheckInterval(interval time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.fileCheckInterval = interval
+	return nil
+}
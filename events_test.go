@@ -0,0 +1,249 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+// collectEvents makes listener to send its status events to the returned channel.
+func collectEvents(listener *Listener) chan StreamEvent {
+	events := make(chan StreamEvent, 100)
+	listener.SetEventHandler(func(event StreamEvent) { events <- event })
+
+	return events
+}
+
+func expectEvent(t *testing.T, events <-chan StreamEvent, want StreamEventType) StreamEvent {
+	t.Helper()
+
+	for {
+		select {
+		case event := <-events:
+			if event.Type != want && (event.Type == EventHeartbeat || event.Type == EventStreamStarted) {
+				continue // not related to the test step
+			}
+			if event.Type != want {
+				t.Fatalf("got %s event, want %s", event.Type, want)
+			}
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s event", want)
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "0123456789")
+
+	out := &syncBuffer{}
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(out))
+	events := collectEvents(listener)
+
+	result := make(chan error, 1)
+	go func() { result <- s.StreamTo(listener, 0) }()
+	waitSubscribed(t, s, listener)
+	waitForOutput(t, out, "0123456789")
+
+	if err := os.Truncate(name, 0); err != nil {
+		t.Fatal(err)
+	}
+	if event := expectEvent(t, events, EventFileTruncated); event.Offset != 10 || event.File != name {
+		t.Errorf("got truncation event %+v, want offset 10 of '%s'", event, name)
+	}
+
+	// rotation: the old file is moved away, a new one replaces it at once, so the path never disappears
+	rotated, replacement := name+".1", name+".new"
+	if err := os.Link(name, rotated); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(rotated)
+	if err := ioutil.WriteFile(replacement, []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(replacement, name); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, events, EventFileRotated)
+
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+	// fsnotify ignores events of removed paths, wake up the stream to notice the removal
+	if err := listener.SeekTo(0, io.SeekCurrent); err != nil {
+		t.Fatal(err)
+	}
+	expectEvent(t, events, EventFileDeleted)
+
+	if err := <-result; err != nil {
+		t.Errorf("stream of removed file ended with %v", err)
+	}
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	s := startTestStreamer(t)
+	listener := NewListener(openTestFile(t, createTestFile(t, "data")), bufio.NewWriter(ioutil.Discard))
+	events := collectEvents(listener)
+	listener.SetHeartbeat(10 * time.Millisecond)
+
+	result := make(chan error, 1)
+	go func() { result <- s.StreamTo(listener, 0) }()
+
+	for i := 0; i < 2; i++ {
+		if event := expectEvent(t, events, EventHeartbeat); event.Offset != 4 {
+			t.Errorf("got heartbeat at offset %d, want 4", event.Offset)
+		}
+	}
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Error(err)
+	}
+}
+
+func TestEventsChannel(t *testing.T) {
+	s := startTestStreamer(t)
+
+	out := &syncBuffer{}
+	listener := NewListener(openTestFile(t, createTestFile(t, "data")), bufio.NewWriter(out))
+	events := listener.Events()
+	listener.Close()
+
+	if err := s.StreamTo(listener, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []StreamEventType
+	for event := range events {
+		got = append(got, event.Type)
+	}
+
+	if len(got) != 2 || got[0] != EventStreamStarted || got[1] != EventStreamStopped {
+		t.Errorf("got events %v, want [stream_started stream_stopped]", got)
+	}
+	if out.String() != "data" {
+		t.Errorf("got data %q, want %q", out.String(), "data")
+	}
+}
+
+func TestEventsKeepDataClean(t *testing.T) {
+	s := startTestStreamer(t)
+
+	dir, err := ioutil.TempDir("", "events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+
+	// reading a directory fails
+	out := &syncBuffer{}
+	listener := NewListener(openTestFile(t, dir), bufio.NewWriter(out))
+	events := listener.Events()
+
+	streamErr := s.StreamTo(listener, 0)
+	if streamErr == nil {
+		t.Fatal("stream of directory did not fail")
+	}
+
+	expectEvent(t, events, EventStreamStarted)
+	if event := expectEvent(t, events, EventError); event.Err != streamErr {
+		t.Errorf("got error event with %v, want %v", event.Err, streamErr)
+	}
+	if event := expectEvent(t, events, EventStreamStopped); event.Err != streamErr {
+		t.Errorf("got stop event with %v, want %v", event.Err, streamErr)
+	}
+
+	if out.String() != "" {
+		t.Errorf("error message %q was written into the data stream", out.String())
+	}
+}
+
+func TestFileCheckInterval(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	if err := s.SetFileCheckInterval(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	name := createTestFile(t, "data")
+	listener, result := startTestStream(t, s, name)
+	events := collectEvents(listener)
+
+	// removal of open file produces no events
+	if err := os.Remove(name); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream of removed file did not stop")
+	}
+	expectEvent(t, events, EventFileDeleted)
+}
+
+// streamAppends streams a file while it gets <appends> writes, returns the number of file checks done by Streamer.
+func streamAppends(tb testing.TB, appends int) uint64 {
+	s := New(log.New(ioutil.Discard, "", 0))
+	if err := s.Start(); err != nil {
+		tb.Fatal(err)
+	}
+	defer s.Stop()
+
+	name := createTestFile(tb, "")
+	file, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	defer file.Close()
+
+	out := &syncBuffer{}
+	listener := NewListener(openTestFile(tb, name), bufio.NewWriter(out))
+	result := make(chan error, 1)
+	go func() { result <- s.StreamTo(listener, 0) }()
+
+	for i := 0; i < appends; i++ {
+		if _, err = file.WriteString("0123456789abcdef"); err != nil {
+			tb.Fatal(err)
+		}
+	}
+
+	for len(out.String()) != appends*16 {
+		time.Sleep(time.Millisecond)
+	}
+	listener.Close()
+	if err = <-result; err != nil {
+		tb.Fatal(err)
+	}
+
+	return s.Metrics().FileChecks
+}
+
+func TestFileChecksOnAppends(t *testing.T) {
+	// only notifications that bring no data (the file was appended while previous data was read) need a check
+	if checks := streamAppends(t, 1000); checks > 500 {
+		t.Errorf("%d file checks for 1000 appends", checks)
+	}
+}
+
+func BenchmarkHighFrequencyAppends(b *testing.B) {
+	const appends = 10000
+
+	var checks uint64
+	for i := 0; i < b.N; i++ {
+		checks += streamAppends(b, appends)
+	}
+
+	b.ReportMetric(float64(checks)/float64(b.N*appends), "stats/append")
+}
@@ -7,53 +7,26 @@
 //     curl "http://localhost:4444/log-stream/LICENCE"
 //
 //   will start streaming of LICENCE file from current directory with 2s timeout on changes.
+//
+//   Run with '-mode http' to get a valid chunked HTTP response instead (or '-mode sse' for Server-Sent Events),
+//   see '-help' for other options.
 
 package main
 
 import (
-	"fmt"
+	"flag"
 	"github.com/badoo/file-streamer"
 	"log"
 	"net/http"
 	"os"
-	"path"
-	"strconv"
-	"time"
 )
 
-type rawStreamHandler struct {
-	http.Handler
-
-	pathPrefix string
-	streamer   *file_streamer.Streamer
-}
-
-func parseOffset(offsetString string) (int64, error) {
-	if offsetString == "" {
-		return 0, nil
-	}
-
-	return strconv.ParseInt(offsetString, 10, 64)
-}
-
-func (h *rawStreamHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	filePath := path.Join(h.pathPrefix, req.URL.Path)
-
-	req.ParseForm()
-
-	offset, err := parseOffset(req.Form.Get("offset"))
-	if err != nil {
-		http.Error(w, fmt.Sprintf("incorrect offset: %s", err.Error()), http.StatusBadRequest)
-		return
-	}
-
-	err = file_streamer.StreamRawData(filePath, offset, h.streamer, w, time.Second*2)
-	if err != nil {
-		log.Println("file streaming error:", err.Error())
-	}
-}
-
 func main() {
+	config := file_streamer.DefaultServerConfig()
+	config.Mode = file_streamer.ModeRaw
+	config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
 	streamer := file_streamer.New(log.New(os.Stderr, "[streamer] ", log.LstdFlags))
 
 	err := streamer.Start()
@@ -61,15 +34,7 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	handler := &rawStreamHandler{
-		pathPrefix: "./", // stream any file in current directory and its subs
-		streamer:   streamer,
-	}
-
-	mux := http.NewServeMux()
-	mux.Handle("/log-stream/", http.StripPrefix("/log-stream/", handler))
-
-	err = http.ListenAndServe(":4444", mux)
+	err = http.ListenAndServe(config.Addr, config.Mux(streamer))
 	if err != http.ErrServerClosed {
 		log.Fatalln(err)
 	}
@@ -0,0 +1,51 @@
+// This example streams file data into another process, like 'tail -f <file> | grep <pattern>' does.
+// Example:
+//     go run ./examples/stream-to-process.go /var/log/syslog ERROR
+//
+//   The stream stops as soon as grep process exits.
+
+package main
+
+import (
+	"github.com/badoo/file-streamer"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/exec"
+)
+
+func main() {
+	nullLogger := log.New(ioutil.Discard, "", 0)
+
+	streamer := file_streamer.New(nullLogger)
+	err := streamer.Start()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	readFrom, err := os.Open(os.Args[1])
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	grep := exec.Command("grep", "--line-buffered", os.Args[2])
+	grep.Stdout = os.Stdout
+	writeTo, err := grep.StdinPipe()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	err = grep.Start()
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	// returns nil when grep exits and closes the pipe
+	err = streamer.StreamTo(file_streamer.NewWriterListener(readFrom, writeTo), 0)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	_ = writeTo.Close()
+	_ = grep.Wait()
+}
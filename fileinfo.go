@@ -0,0 +1,168 @@
+package file_streamer
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	defaultExactLinesLimit = 16 * 1024 * 1024 // files up to this size get exact line count by default
+	lineSampleSize         = 64 * 1024        // line count of bigger files is estimated by samples of this size
+)
+
+// FileStats is the result of FileInfo().
+type FileStats struct {
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mtime"`
+	Lines      int64     `json:"lines"`       // the last line is counted even without trailing line feed
+	LinesExact bool      `json:"lines_exact"` // false when Lines is an estimation
+}
+
+// FileInfoOptions configures FileInfo().
+type FileInfoOptions struct {
+	// Files up to this size get exact line count, lines of bigger files are estimated by the average length of lines
+	// at the beginning and at the end of the file. Zero means 16MB, negative value means 'always count exactly'.
+	ExactLimit int64
+
+	// Cache remembers line counts, so only the data appended since the previous call is scanned. Lines are always
+	// counted exactly with cache. Nil means 'no caching'.
+	Cache *LineCountCache
+}
+
+// FileInfo returns size, modification time and line count of the file at <path>, so frontends can render scrollbars
+// and 'start at line N' pickers.
+func FileInfo(path string, options FileInfoOptions) (FileStats, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return FileStats{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return FileStats{}, err
+	}
+
+	stats := FileStats{Size: info.Size(), ModTime: info.ModTime(), LinesExact: true}
+
+	exactLimit := options.ExactLimit
+	if exactLimit == 0 {
+		exactLimit = defaultExactLinesLimit
+	}
+
+	var newLines int64
+	switch {
+	case options.Cache != nil:
+		newLines, err = options.Cache.count(path, file, info)
+	case exactLimit < 0 || info.Size() <= exactLimit || info.Size() <= 2*lineSampleSize:
+		newLines, err = countNewLines(file, 0, info.Size())
+	default:
+		stats.Lines, err = estimateLines(file, info.Size())
+		stats.LinesExact = false
+		return stats, err
+	}
+	if err != nil {
+		return FileStats{}, err
+	}
+
+	stats.Lines = newLines
+	if info.Size() > 0 {
+		last := make([]byte, 1)
+		if n, _ := file.ReadAt(last, info.Size()-1); n == 1 && last[0] != '\n' {
+			stats.Lines++ // the last line has no line feed
+		}
+	}
+
+	return stats, nil
+}
+
+// countNewLines returns the number of line feeds in the data of <file> between <start> and <end> offsets.
+func countNewLines(file *os.File, start, end int64) (int64, error) {
+	var count int64
+
+	buf := make([]byte, 64*1024)
+	for offset := start; offset < end; {
+		size := int64(len(buf))
+		if end-offset < size {
+			size = end - offset
+		}
+
+		n, err := file.ReadAt(buf[:size], offset)
+		count += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		offset += int64(n)
+
+		if err == io.EOF {
+			break // the file was truncated meanwhile
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return count, nil
+}
+
+// estimateLines estimates the number of lines in <file> of <size> bytes by the average length of lines at its
+// beginning and at its end.
+func estimateLines(file *os.File, size int64) (int64, error) {
+	head, err := countNewLines(file, 0, lineSampleSize)
+	if err != nil {
+		return 0, err
+	}
+
+	tail, err := countNewLines(file, size-lineSampleSize, size)
+	if err != nil {
+		return 0, err
+	}
+
+	if head+tail == 0 {
+		return 1, nil
+	}
+
+	return size * (head + tail) / (2 * lineSampleSize), nil
+}
+
+// LineCountCache remembers line counts of files for FileInfo(). It is safe for concurrent use.
+type LineCountCache struct {
+	mu    sync.Mutex
+	files map[string]lineCount
+}
+
+type lineCount struct {
+	info     os.FileInfo // to find out the file was replaced
+	size     int64       // the amount of counted data
+	newLines int64
+}
+
+// NewLineCountCache creates an empty LineCountCache.
+func NewLineCountCache() *LineCountCache {
+	return &LineCountCache{files: make(map[string]lineCount)}
+}
+
+// count returns the number of line feeds in <file>, scanning only the data appended since the previous call.
+func (c *LineCountCache) count(path string, file *os.File, info os.FileInfo) (int64, error) {
+	c.mu.Lock()
+	cached, found := c.files[path]
+	c.mu.Unlock()
+
+	// the file was replaced or truncated: count from scratch
+	if !found || !os.SameFile(cached.info, info) || info.Size() < cached.size {
+		cached = lineCount{}
+	}
+
+	appended, err := countNewLines(file, cached.size, info.Size())
+	if err != nil {
+		return 0, err
+	}
+
+	counted := lineCount{info: info, size: info.Size(), newLines: cached.newLines + appended}
+
+	c.mu.Lock()
+	c.files[path] = counted
+	c.mu.Unlock()
+
+	return counted.newLines, nil
+}
@@ -0,0 +1,82 @@
+package file_streamer
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileInfo(t *testing.T) {
+	name := createTestFile(t, "one\ntwo\nthree")
+
+	stats, err := FileInfo(name, FileInfoOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Size != 13 || stats.Lines != 3 || !stats.LinesExact || stats.ModTime.IsZero() {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+
+	if stats, _ = FileInfo(createTestFile(t, ""), FileInfoOptions{}); stats.Lines != 0 {
+		t.Errorf("empty file has %d lines", stats.Lines)
+	}
+
+	if _, err = FileInfo("/non/existent/file", FileInfoOptions{}); err == nil {
+		t.Error("info of non-existent file succeeded")
+	}
+}
+
+func TestFileInfoEstimation(t *testing.T) {
+	name := createTestFile(t, strings.Repeat("0123456789\n", 100000)) // 1.1MB
+
+	stats, err := FileInfo(name, FileInfoOptions{ExactLimit: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.LinesExact || stats.Lines < 99000 || stats.Lines > 101000 {
+		t.Errorf("unexpected estimation %+v", stats)
+	}
+}
+
+func TestFileInfoCache(t *testing.T) {
+	name := createTestFile(t, "one\ntwo\n")
+	cache := NewLineCountCache()
+
+	if stats, _ := FileInfo(name, FileInfoOptions{Cache: cache}); stats.Lines != 2 {
+		t.Errorf("got %d lines, want 2", stats.Lines)
+	}
+
+	appendToFile(t, name, "three\nfour")
+	if stats, _ := FileInfo(name, FileInfoOptions{Cache: cache}); stats.Lines != 4 {
+		t.Errorf("got %d lines after append, want 4", stats.Lines)
+	}
+
+	// truncated file is counted from scratch
+	if err := os.Truncate(name, 4); err != nil {
+		t.Fatal(err)
+	}
+	if stats, _ := FileInfo(name, FileInfoOptions{Cache: cache}); stats.Lines != 1 {
+		t.Errorf("got %d lines after truncation, want 1", stats.Lines)
+	}
+}
+
+func TestHandlerInfo(t *testing.T) {
+	server, root := startTestServer(t, DefaultServerConfig())
+	writeRootFile(t, root, "app.log", "one\ntwo\n")
+
+	resp, err := http.Get(server.URL + "/log-stream/app.log?info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var stats FileStats
+	if err = json.NewDecoder(resp.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Size != 8 || stats.Lines != 2 || !stats.LinesExact {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+}
@@ -0,0 +1,39 @@
+package file_streamer
+
+import "time"
+
+// FlushPolicy defines when Streamer flushes Listener's buffered writer. The zero value flushes after each portion of
+// file data, which makes chatty writers to produce lots of tiny WebSocket messages or HTTP chunks.
+//
+// With MinBytes set, data is flushed once at least MinBytes are buffered (the writer still flushes itself when its
+// buffer is full). With MaxDelay set, buffered data waits for the flush not longer than MaxDelay, so data is flushed
+// at most once per MaxDelay when MinBytes is not set. Buffered data is always flushed when the stream stops.
+type FlushPolicy struct {
+	MinBytes int
+	MaxDelay time.Duration // 1 second by default when MinBytes is set
+}
+
+// FlushImmediately is the default policy: flush after each portion of file data.
+var FlushImmediately = FlushPolicy{}
+
+// SetFlushPolicy defines when Streamer flushes listener's buffered writer.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetFlushPolicy(policy FlushPolicy) {
+	if policy.MinBytes > 0 && policy.MaxDelay <= 0 {
+		policy.MaxDelay = time.Second
+	}
+
+	bs.mu.Lock()
+	bs.flushPolicy = policy
+	bs.mu.Unlock()
+}
+
+// due reports whether <buffered> bytes should be flushed right away.
+func (p FlushPolicy) due(buffered int) bool {
+	if p == FlushImmediately || buffered == 0 {
+		return true
+	}
+
+	return p.MinBytes > 0 && buffered >= p.MinBytes
+}
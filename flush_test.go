@@ -0,0 +1,65 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestFlushPolicyMinBytes(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "12345")
+
+	out := &syncBuffer{}
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(out))
+	listener.SetFlushPolicy(FlushPolicy{MinBytes: 10, MaxDelay: time.Minute})
+
+	result := make(chan error)
+	go func() { result <- s.StreamTo(listener, 0) }()
+
+	time.Sleep(20 * time.Millisecond)
+	if out.String() != "" {
+		t.Fatalf("%q was flushed before min bytes were collected", out.String())
+	}
+
+	appendToFile(t, name, "67890")
+	waitForOutput(t, out, "1234567890")
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlushPolicyMaxDelay(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "data")
+
+	out := &syncBuffer{}
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(out))
+	listener.SetFlushPolicy(FlushPolicy{MaxDelay: 5 * time.Millisecond})
+
+	result := make(chan error)
+	go func() { result <- s.StreamTo(listener, 0) }()
+	waitForOutput(t, out, "data")
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFlushPolicyFlushesOnStop(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "held data")
+
+	var out bytes.Buffer
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(&out))
+	listener.SetFlushPolicy(FlushPolicy{MinBytes: 1000, MaxDelay: time.Minute})
+	catFile(t, s, listener)
+
+	if out.String() != "held data" {
+		t.Errorf("got %q after the stream stopped, want %q", out.String(), "held data")
+	}
+}
@@ -0,0 +1,94 @@
+package file_streamer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// defaultChunkSize is the default limit of Chunk data size.
+const defaultChunkSize = 64 * 1024
+
+// Chunk is a portion of file data delivered by Streamer.Follow().
+type Chunk struct {
+	Data   []byte // owned by the receiver
+	Offset int64  // file offset of Data, valid as long as the file is only appended to
+	Err    error  // the error the stream stopped with, Data is empty then
+}
+
+// FollowOptions configures Streamer.Follow().
+type FollowOptions struct {
+	Offset    int64         // where to start streaming from
+	Timeout   time.Duration // see Streamer.StreamTo()
+	ChunkSize int           // the limit of Chunk data size, 64KB by default
+	Labels    Labels        // see Listener.SetLabels()
+}
+
+// Follow streams the file at <path> into the returned channel, for Go programs that embed Streamer and want the data
+// as is, without a bufio.Writer in between. The channel is closed when the stream stops: <ctx> is done, the file is
+// removed or options.Timeout expires. When the stream stops because of an error, the last Chunk holds it in Err.
+//
+// The stream waits for the receiver: a slow receiver slows the stream down, nothing is dropped. Cancel <ctx> to stop
+// receiving, the channel is closed soon after that.
+func (s *Streamer) Follow(ctx context.Context, path string, options FollowOptions) (<-chan Chunk, error) {
+	if !s.IsRunning() {
+		return nil, ErrNotRunning
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = file.Seek(options.Offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	chunkSize := options.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultChunkSize
+	}
+
+	chunks := make(chan Chunk)
+	writer := &chunkWriter{ctx: ctx, chunks: chunks, offset: options.Offset}
+
+	listener := NewListener(file, bufio.NewWriterSize(writer, chunkSize))
+	listener.SetLabels(options.Labels)
+	listener.SetEventHandler(func(StreamEvent) {}) // errors come in Chunk.Err, not as error text in Chunk.Data
+
+	go func() {
+		defer close(chunks)
+		defer file.Close()
+
+		if err := s.StreamToContext(ctx, listener, options.Timeout); err != nil && err != ctx.Err() {
+			select {
+			case chunks <- Chunk{Offset: writer.offset, Err: err}:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	return chunks, nil
+}
+
+// chunkWriter sends each write to the channel as a Chunk, waiting for the receiver.
+type chunkWriter struct {
+	ctx    context.Context
+	chunks chan<- Chunk
+	offset int64 // offset of the next chunk, changed by streaming goroutine only
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	chunk := Chunk{Data: append([]byte(nil), p...), Offset: w.offset}
+
+	select {
+	case w.chunks <- chunk:
+		w.offset += int64(len(p))
+		return len(p), nil
+	case <-w.ctx.Done():
+		return 0, w.ctx.Err()
+	}
+}
@@ -0,0 +1,91 @@
+package file_streamer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+// receiveChunk returns the next chunk from <chunks>, failing the test when there is none in 5 seconds.
+func receiveChunk(t *testing.T, chunks <-chan Chunk) Chunk {
+	select {
+	case chunk, ok := <-chunks:
+		if !ok {
+			t.Fatal("chunks channel is closed")
+		}
+		return chunk
+	case <-time.After(5 * time.Second):
+		t.Fatal("no chunk in 5 seconds")
+	}
+
+	return Chunk{}
+}
+
+func TestFollow(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "skipped\nfirst\n")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	chunks, err := s.Follow(ctx, name, FollowOptions{Offset: 8})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if chunk := receiveChunk(t, chunks); string(chunk.Data) != "first\n" || chunk.Offset != 8 {
+		t.Errorf("got chunk %q at %d, want %q at 8", chunk.Data, chunk.Offset, "first\n")
+	}
+
+	for i := 0; len(s.ActiveStreams()) == 0 && i < 2000; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	appendToFile(t, name, "second\n")
+
+	if chunk := receiveChunk(t, chunks); string(chunk.Data) != "second\n" || chunk.Offset != 14 {
+		t.Errorf("got chunk %q at %d, want %q at 14", chunk.Data, chunk.Offset, "second\n")
+	}
+
+	cancel()
+	for range chunks {
+	}
+}
+
+func TestFollowStopsOnTimeout(t *testing.T) {
+	s := startTestStreamer(t)
+
+	chunks, err := s.Follow(context.Background(), createTestFile(t, "data"), FollowOptions{Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	receiveChunk(t, chunks)
+	if chunk, ok := <-chunks; ok {
+		t.Errorf("got chunk %+v after the stream timeout, want closed channel", chunk)
+	}
+}
+
+func TestFollowReportsErrorsInErr(t *testing.T) {
+	s := startTestStreamer(t)
+
+	dir, err := ioutil.TempDir("", "follow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(dir)
+
+	// reading a directory fails
+	chunks, err := s.Follow(context.Background(), dir, FollowOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if chunk := receiveChunk(t, chunks); chunk.Err == nil || len(chunk.Data) != 0 {
+		t.Errorf("got chunk %q with error %v, want the error in Err only", chunk.Data, chunk.Err)
+	}
+	if chunk, ok := <-chunks; ok {
+		t.Errorf("got chunk %+v after the error, want closed channel", chunk)
+	}
+}
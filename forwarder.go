@@ -0,0 +1,351 @@
+package file_streamer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Batch is a portion of file data sent to a Sink by Forwarder.
+type Batch struct {
+	File   string // path of the file, as it was matched by ForwarderConfig.Files
+	Offset int64  // position of the first byte of Data in the file
+	Data   []byte
+}
+
+// Sink receives file data from Forwarder.
+//
+// Send() is called concurrently for different files, but never concurrently for the same file. The batch is
+// considered delivered when Send() returns nil, otherwise Forwarder retries it with backoff. Data must not be
+// retained after Send() returns.
+type Sink interface {
+	Send(ctx context.Context, batch Batch) error
+}
+
+// SinkFunc is an adapter to allow the use of ordinary functions as sinks.
+type SinkFunc func(ctx context.Context, batch Batch) error
+
+// Send calls f(ctx, batch).
+func (f SinkFunc) Send(ctx context.Context, batch Batch) error {
+	return f(ctx, batch)
+}
+
+// ErrNoSink is returned by NewForwarder() when config has no Sink.
+var ErrNoSink = errors.New("forwarder sink is not configured")
+
+// ForwarderConfig describes what Forwarder tails and where it sends the data.
+type ForwarderConfig struct {
+	Files []string // file paths or glob patterns (see filepath.Match)
+	Sink  Sink
+
+	// Checkpoints record offsets of delivered data, so a restarted Forwarder resumes where it left off.
+	// Nil means 'start from scratch each time'.
+	Checkpoints CheckpointStore
+	ConsumerID  string // consumer name for Checkpoints, "forwarder" by default
+
+	StartAtEnd bool // start files without checkpoints from their end instead of the beginning
+
+	BatchSize     int           // send data once this many bytes are collected, 64KB by default
+	FlushInterval time.Duration // send incomplete batch after this delay, 1 second by default
+
+	RetryMinBackoff time.Duration // delay before the first retry of failed Send(), 100ms by default
+	RetryMaxBackoff time.Duration // backoff doubles on each retry up to this limit, 30 seconds by default
+
+	RescanInterval time.Duration // how often glob patterns are matched again to find new files, 10 seconds by default
+}
+
+// Forwarder continuously tails files with Streamer and forwards their data to a Sink: it batches data, retries
+// failed deliveries with backoff and checkpoints delivered offsets. Delivery is 'at least once': a batch that was sent,
+// but not checkpointed before the process died, is sent again after restart.
+//
+// Rotated files are picked up again on the next rescan after the old file is removed.
+type Forwarder struct {
+	streamer *Streamer
+	config   ForwarderConfig
+
+	mu      sync.Mutex
+	running map[string]empty // files being forwarded right now
+	streams sync.WaitGroup
+}
+
+// NewForwarder creates a Forwarder that uses <streamer> for tailing files. <streamer> must be running when
+// Forwarder.Run() is called.
+func NewForwarder(streamer *Streamer, config ForwarderConfig) (*Forwarder, error) {
+	if config.Sink == nil {
+		return nil, ErrNoSink
+	}
+
+	for _, pattern := range config.Files {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	if config.ConsumerID == "" {
+		config.ConsumerID = "forwarder"
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 64 * 1024
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	if config.RetryMinBackoff <= 0 {
+		config.RetryMinBackoff = 100 * time.Millisecond
+	}
+	if config.RetryMaxBackoff < config.RetryMinBackoff {
+		config.RetryMaxBackoff = 30 * time.Second
+	}
+	if config.RescanInterval <= 0 {
+		config.RescanInterval = 10 * time.Second
+	}
+
+	return &Forwarder{
+		streamer: streamer,
+		config:   config,
+		running:  make(map[string]empty),
+	}, nil
+}
+
+// Run forwards files until <ctx> is done. It returns ctx.Err() after all streams are stopped; data collected but not
+// delivered by that moment is sent again on the next Run() when checkpoints are configured.
+func (f *Forwarder) Run(ctx context.Context) error {
+	if !f.streamer.IsRunning() {
+		return ErrNotRunning
+	}
+
+	rescanTimer := f.streamer.clock.NewTimer(f.config.RescanInterval)
+	defer rescanTimer.Stop()
+
+	for {
+		f.scan(ctx)
+
+		select {
+		case <-rescanTimer.C():
+			rescanTimer.Reset(f.config.RescanInterval)
+		case <-ctx.Done():
+			f.streams.Wait()
+			return ctx.Err()
+		}
+	}
+}
+
+// scan starts forwarding of matched files that are not forwarded yet.
+func (f *Forwarder) scan(ctx context.Context) {
+	for _, pattern := range f.config.Files {
+		matches, _ := filepath.Glob(pattern) // patterns were validated by NewForwarder()
+
+		for _, path := range matches {
+			f.mu.Lock()
+			_, isRunning := f.running[path]
+			if !isRunning {
+				f.running[path] = empty{}
+			}
+			f.mu.Unlock()
+
+			if isRunning {
+				continue
+			}
+
+			f.streams.Add(1)
+			go f.forward(ctx, path)
+		}
+	}
+}
+
+func (f *Forwarder) forward(ctx context.Context, path string) {
+	defer f.streams.Done()
+	defer func() {
+		f.mu.Lock()
+		delete(f.running, path)
+		f.mu.Unlock()
+	}()
+
+	err := f.stream(ctx, path)
+	if err != nil && ctx.Err() == nil {
+		f.streamer.logger.Printf("File '%s' forwarding error: %s", path, err.Error())
+	}
+}
+
+func (f *Forwarder) stream(ctx context.Context, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	offset, err := f.startOffset(file)
+	if err != nil {
+		return err
+	}
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	batcher := &forwardBatcher{
+		forwarder: f,
+		ctx:       ctx,
+		file:      path,
+		offset:    offset,
+		timer:     getTimer(f.streamer.clock, 0),
+		done:      make(chan empty),
+	}
+	go batcher.flushOnTimer()
+
+	listener := NewListener(file, bufio.NewWriterSize(batcher, f.config.BatchSize))
+	err = f.streamer.StreamToContext(ctx, listener, 0)
+
+	close(batcher.done)
+	if err != nil || ctx.Err() != nil {
+		return err
+	}
+
+	// the file was removed: deliver its last piece of data and forget the offset, so a new file created at the same
+	// path (after log rotation) is forwarded from the beginning
+	if err = batcher.flush(); err != nil {
+		return err
+	}
+
+	if f.config.Checkpoints != nil {
+		return f.config.Checkpoints.Save(path, f.config.ConsumerID, 0)
+	}
+
+	return nil
+}
+
+// startOffset returns the offset to start forwarding of <file> from.
+func (f *Forwarder) startOffset(file *os.File) (int64, error) {
+	if f.config.Checkpoints != nil {
+		offset, found, err := f.config.Checkpoints.Load(file.Name(), f.config.ConsumerID)
+		if err != nil {
+			return 0, err
+		}
+
+		info, err := file.Stat()
+		if err != nil {
+			return 0, err
+		}
+
+		if found && offset <= info.Size() {
+			return offset, nil
+		}
+		if found {
+			return 0, nil // the file was truncated or replaced
+		}
+	}
+
+	if f.config.StartAtEnd {
+		return file.Seek(0, io.SeekEnd)
+	}
+
+	return 0, nil
+}
+
+// send delivers <batch> to the sink, retrying with backoff until it succeeds or <ctx> is done.
+func (f *Forwarder) send(ctx context.Context, batch Batch) error {
+	backoff := f.config.RetryMinBackoff
+
+	for {
+		err := f.config.Sink.Send(ctx, batch)
+		if err == nil {
+			return nil
+		}
+
+		f.streamer.logger.Printf("File '%s' batch delivery error, retrying in %s: %s", batch.File, backoff, err.Error())
+
+		timer := f.streamer.clock.NewTimer(backoff)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		if backoff *= 2; backoff > f.config.RetryMaxBackoff {
+			backoff = f.config.RetryMaxBackoff
+		}
+	}
+}
+
+// forwardBatcher collects file data written by Streamer into batches and sends them to Forwarder's sink.
+type forwardBatcher struct {
+	forwarder *Forwarder
+	ctx       context.Context
+	file      string
+
+	mu      sync.Mutex
+	pending []byte
+	offset  int64 // file offset of pending[0]
+	armed   bool  // timer is running for pending data
+
+	timer Timer
+	done  chan empty
+}
+
+// Write is called by Listener's buffered writer each time Streamer flushes file data.
+func (b *forwardBatcher) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pending = append(b.pending, p...)
+	if len(b.pending) >= b.forwarder.config.BatchSize {
+		return len(p), b.flushLocked()
+	}
+
+	if !b.armed {
+		b.timer.Reset(b.forwarder.config.FlushInterval)
+		b.armed = true
+	}
+
+	return len(p), nil
+}
+
+func (b *forwardBatcher) flushOnTimer() {
+	for {
+		select {
+		case <-b.timer.C():
+			b.mu.Lock()
+			b.armed = false
+			if err := b.flushLocked(); err != nil && b.ctx.Err() == nil {
+				b.forwarder.streamer.logger.Printf("File '%s' forwarding error: %s", b.file, err.Error())
+			}
+			b.mu.Unlock()
+		case <-b.done:
+			b.timer.Stop()
+			return
+		}
+	}
+}
+
+func (b *forwardBatcher) flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.flushLocked()
+}
+
+func (b *forwardBatcher) flushLocked() error {
+	if len(b.pending) == 0 {
+		return nil
+	}
+
+	batch := Batch{File: b.file, Offset: b.offset, Data: b.pending}
+	if err := b.forwarder.send(b.ctx, batch); err != nil {
+		return err
+	}
+
+	b.offset += int64(len(b.pending))
+	b.pending = b.pending[:0]
+
+	if checkpoints := b.forwarder.config.Checkpoints; checkpoints != nil {
+		return checkpoints.Save(b.file, b.forwarder.config.ConsumerID, b.offset)
+	}
+
+	return nil
+}
@@ -0,0 +1,226 @@
+package file_streamer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingSink collects delivered data per file and fails the first <failures> deliveries.
+type recordingSink struct {
+	mu        sync.Mutex
+	failures  int
+	delivered map[string][]byte
+	offsets   map[string][]int64
+}
+
+func newRecordingSink(failures int) *recordingSink {
+	return &recordingSink{
+		failures:  failures,
+		delivered: make(map[string][]byte),
+		offsets:   make(map[string][]int64),
+	}
+}
+
+func (r *recordingSink) Send(ctx context.Context, batch Batch) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.failures > 0 {
+		r.failures--
+		return errors.New("upstream is down")
+	}
+
+	r.delivered[batch.File] = append(r.delivered[batch.File], batch.Data...)
+	r.offsets[batch.File] = append(r.offsets[batch.File], batch.Offset)
+	return nil
+}
+
+func (r *recordingSink) waitFor(t *testing.T, file, data string) {
+	for i := 0; i < 2000; i++ {
+		r.mu.Lock()
+		got := string(r.delivered[file])
+		r.mu.Unlock()
+
+		if got == data {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("%s: data %q was not delivered", file, data)
+}
+
+func startForwarder(t *testing.T, s *Streamer, config ForwarderConfig) {
+	config.FlushInterval = 5 * time.Millisecond
+	config.RetryMinBackoff = time.Millisecond
+	config.RescanInterval = 5 * time.Millisecond
+
+	forwarder, err := NewForwarder(s, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- forwarder.Run(ctx) }()
+
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != context.Canceled {
+			t.Errorf("Run() returned %v, want %v", err, context.Canceled)
+		}
+	})
+}
+
+func appendToFile(t *testing.T, name, data string) {
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestForwarderRetriesAndCheckpoints(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "first\n")
+
+	sink := newRecordingSink(3)
+	checkpoints := NewMemoryCheckpointStore()
+	startForwarder(t, s, ForwarderConfig{Files: []string{name}, Sink: sink, Checkpoints: checkpoints})
+
+	sink.waitFor(t, name, "first\n")
+	appendToFile(t, name, "second\n")
+	sink.waitFor(t, name, "first\nsecond\n")
+
+	for i := 0; i < 1000; i++ {
+		if offset, _, _ := checkpoints.Load(name, "forwarder"); offset == 13 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Error("delivered offset was not checkpointed")
+}
+
+func TestForwarderResumesFromCheckpoint(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "delivered\nnew\n")
+
+	checkpoints := NewMemoryCheckpointStore()
+	checkpoints.Save(name, "shipper", 10)
+
+	sink := newRecordingSink(0)
+	startForwarder(t, s, ForwarderConfig{Files: []string{name}, Sink: sink, Checkpoints: checkpoints, ConsumerID: "shipper"})
+
+	sink.waitFor(t, name, "new\n")
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if sink.offsets[name][0] != 10 {
+		t.Errorf("first batch offset %d, want 10", sink.offsets[name][0])
+	}
+}
+
+func TestForwarderGlobFindsNewFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-streamer-forwarder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	s := startTestStreamer(t)
+	sink := newRecordingSink(0)
+	startForwarder(t, s, ForwarderConfig{Files: []string{filepath.Join(dir, "*.log")}, Sink: sink})
+
+	appendToFile(t, filepath.Join(dir, "a.log"), "a")
+	appendToFile(t, filepath.Join(dir, "b.txt"), "ignored")
+	sink.waitFor(t, filepath.Join(dir, "a.log"), "a")
+
+	appendToFile(t, filepath.Join(dir, "c.log"), "c")
+	sink.waitFor(t, filepath.Join(dir, "c.log"), "c")
+}
+
+func TestNewForwarderValidatesConfig(t *testing.T) {
+	s := New(nil)
+
+	if _, err := NewForwarder(s, ForwarderConfig{Files: []string{"*.log"}}); err != ErrNoSink {
+		t.Errorf("got %v for config without sink, want %v", err, ErrNoSink)
+	}
+
+	if _, err := NewForwarder(s, ForwarderConfig{Files: []string{"["}, Sink: newRecordingSink(0)}); err == nil {
+		t.Error("broken glob pattern was accepted")
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		got = append(got, r.Header.Get(HeaderFile), r.Header.Get(HeaderOffset), string(body))
+
+		if r.Header.Get(HeaderOffset) == strconv.Itoa(500) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL, nil)
+	if err := sink.Send(context.Background(), Batch{File: "/var/log/app.log", Offset: 42, Data: []byte("data")}); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"/var/log/app.log", "42", "data"}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request field %d is %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	if err := sink.Send(context.Background(), Batch{Offset: 500}); err == nil {
+		t.Error("503 response was not reported as an error")
+	}
+}
+
+func TestTCPSink(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		data, _ := ioutil.ReadAll(conn)
+		received <- data
+	}()
+
+	sink := NewTCPSink(ln.Addr().String(), time.Second)
+	for _, data := range []string{"one\n", "two\n"} {
+		if err = sink.Send(context.Background(), Batch{Data: []byte(data)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sink.(*tcpSink).conn.Close()
+
+	if data := <-received; !bytes.Equal(data, []byte("one\ntwo\n")) {
+		t.Errorf("received %q, want %q", data, "one\ntwo\n")
+	}
+}
@@ -0,0 +1,100 @@
+package file_streamer
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamError is an error of one stream of ListenerGroup.
+type StreamError struct {
+	File string
+	Err  error
+}
+
+func (e *StreamError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+func (e *StreamError) Unwrap() error {
+	return e.Err
+}
+
+// GroupError is returned by ListenerGroup.Wait() when some streams of the group failed.
+type GroupError struct {
+	Errors []*StreamError
+}
+
+func (e *GroupError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "; ")
+}
+
+// ListenerGroup owns several listeners streamed by the same Streamer, so a session tailing several files can be torn
+// down at once, e.g. when client's connection drops.
+type ListenerGroup struct {
+	streamer *Streamer
+
+	mu        sync.Mutex
+	listeners []*Listener
+	errors    []*StreamError
+	closed    bool
+
+	streams sync.WaitGroup
+}
+
+// NewListenerGroup creates an empty group of listeners streamed by <streamer>.
+func NewListenerGroup(streamer *Streamer) *ListenerGroup {
+	return &ListenerGroup{streamer: streamer}
+}
+
+// Stream starts streaming to <listener> in a separate goroutine, the same way Streamer.StreamTo() does. Listener added
+// after CloseAll() is closed right away, so it reads its file exactly once (see Listener.Close()).
+func (g *ListenerGroup) Stream(listener *Listener, timeout time.Duration) {
+	g.mu.Lock()
+	g.listeners = append(g.listeners, listener)
+	if g.closed {
+		listener.Close()
+	}
+	g.mu.Unlock()
+
+	g.streams.Add(1)
+	go func() {
+		defer g.streams.Done()
+
+		if err := g.streamer.StreamTo(listener, timeout); err != nil {
+			g.mu.Lock()
+			g.errors = append(g.errors, &StreamError{File: listener.file.Name(), Err: err})
+			g.mu.Unlock()
+		}
+	}()
+}
+
+// CloseAll closes all listeners of the group.
+func (g *ListenerGroup) CloseAll() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closed = true
+	for _, listener := range g.listeners {
+		listener.Close()
+	}
+}
+
+// Wait waits for all streams of the group to stop. Returns *GroupError with errors of failed streams, if any.
+func (g *ListenerGroup) Wait() error {
+	g.streams.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if len(g.errors) == 0 {
+		return nil
+	}
+
+	return &GroupError{Errors: append([]*StreamError(nil), g.errors...)}
+}
@@ -0,0 +1,60 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestListenerGroupCloseAll(t *testing.T) {
+	s := startTestStreamer(t)
+	group := NewListenerGroup(s)
+
+	outputs := make([]bytes.Buffer, 3)
+	for i := range outputs {
+		name := createTestFile(t, "data")
+		group.Stream(NewListener(openTestFile(t, name), bufio.NewWriter(&outputs[i])), 0)
+	}
+
+	group.CloseAll()
+	if err := group.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range outputs {
+		if outputs[i].String() != "data" {
+			t.Errorf("stream %d got %q, want %q", i, outputs[i].String(), "data")
+		}
+	}
+}
+
+type failingWriter struct{}
+
+var errWriteFailed = errors.New("write failed")
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errWriteFailed
+}
+
+func TestListenerGroupErrors(t *testing.T) {
+	s := startTestStreamer(t)
+	group := NewListenerGroup(s)
+	group.CloseAll() // listeners added after CloseAll() read their files once
+
+	failed := createTestFile(t, "data")
+	group.Stream(NewListener(openTestFile(t, failed), bufio.NewWriter(failingWriter{})), 0)
+
+	var out bytes.Buffer
+	group.Stream(NewListener(openTestFile(t, createTestFile(t, "data")), bufio.NewWriter(&out)), 0)
+
+	err := group.Wait()
+	groupErr, ok := err.(*GroupError)
+	if !ok {
+		t.Fatalf("Wait() returned %v, want *GroupError", err)
+	}
+
+	if len(groupErr.Errors) != 1 || groupErr.Errors[0].File != failed || !errors.Is(groupErr.Errors[0], errWriteFailed) {
+		t.Errorf("unexpected group errors: %v", groupErr)
+	}
+}
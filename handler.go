@@ -0,0 +1,378 @@
+package file_streamer
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// StreamMode selects the protocol Handler streams files with.
+type StreamMode uint8
+
+const (
+	ModeHTTP      StreamMode = iota // chunked HTTP response, see StreamHTTP()
+	ModeRaw                         // raw data in hijacked connection, see StreamRawData()
+	ModeWebSocket                   // WebSocket messages, see StreamWebSocket()
+	ModeSSE                         // Server-Sent Events, see StreamSSE()
+	ModeZip                         // snapshot of files in zip archive, see StreamArchive()
+	ModeTarGz                       // snapshot of files in tar.gz archive, see StreamArchive()
+)
+
+var streamModeNames = map[StreamMode]string{
+	ModeHTTP:      "http",
+	ModeRaw:       "raw",
+	ModeWebSocket: "websocket",
+	ModeSSE:       "sse",
+	ModeZip:       "zip",
+	ModeTarGz:     "tar.gz",
+}
+
+// ErrUnknownStreamMode is returned by StreamMode.Set() for unknown mode names.
+var ErrUnknownStreamMode = errors.New("unknown stream mode")
+
+func (m StreamMode) String() string {
+	if name, known := streamModeNames[m]; known {
+		return name
+	}
+
+	return "unknown"
+}
+
+// Set parses mode name ('http', 'raw', 'websocket', 'sse', 'zip' or 'tar.gz'), so StreamMode can be used as flag.Value.
+func (m *StreamMode) Set(name string) error {
+	for mode, modeName := range streamModeNames {
+		if modeName == name {
+			*m = mode
+			return nil
+		}
+	}
+
+	return ErrUnknownStreamMode
+}
+
+// HandlerOptions configures Handler.
+type HandlerOptions struct {
+	Mode    StreamMode
+	Timeout time.Duration // see Streamer.StreamTo()
+
+	ResumeWindow int64 // how far from 'offset' resume point is looked for, see FindResumeOffset()
+
+	Trailer bool // send StreamSummary when the stream ends, see HTTPStreamOptions.Trailer and WebSocketOptions.Trailer
+}
+
+// Handler returns http.Handler that streams files from <root> directory: the request path (relative to <root>) picks
+// the file, 'offset' query parameter sets the initial offset. Paths can't escape <root>. Reconnecting clients that are
+// not sure about their offset add 'resume_length' and 'resume_hash' parameters of ResumeRequest: the stream starts
+// at the exact resume point near 'offset' then, or the request fails with 409 Conflict when there is no such point.
+//
+// 'search' query parameter makes Handler reply with JSON list of SearchMatch for the regular expression instead of
+// streaming, 'context' and 'max' parameters set the number of context lines and matches (see Search()), up to 100 and
+// 1000. Offsets of matches are ready to be used as 'offset' of the stream.
+//
+// 'line' query parameter sets the initial offset to the beginning of the line with this number (counting from 0)
+// instead, the line is found with Streamer's line index (see Streamer.SetLineIndexInterval()) when the file is indexed.
+//
+// 'info' query parameter makes Handler reply with JSON of FileStats instead of streaming (see FileInfo()). Lines are
+// counted exactly, the counts are cached.
+//
+// 'download' query parameter ('zip' or 'tar.gz') makes Handler send a snapshot of the file in archive instead of
+// following it, the same happens for all requests in ModeZip and ModeTarGz modes. Archived paths may be glob patterns
+// (see filepath.Match) to download all matched files at once, existing files are never taken for patterns. Files
+// leading out of the root through symlinks are not archived.
+//
+// Use http.StripPrefix() to serve files under a path prefix:
+//
+//	mux.Handle("/log-stream/", http.StripPrefix("/log-stream/", file_streamer.Handler(streamer, "/var/log", options)))
+//
+// Stream errors are logged with Streamer's logger. Use NewReloadableHandler() to change root and options without a restart.
+func Handler(streamer *Streamer, root string, options HandlerOptions) http.Handler {
+	return &streamHandler{streamer: streamer, root: root, options: options, lineCounts: NewLineCountCache()}
+}
+
+type streamHandler struct {
+	streamer *Streamer
+	root     string
+	options  HandlerOptions
+
+	lineCounts *LineCountCache // for 'info' requests
+
+	// authorize checks access to each archived file, nil means 'everything under root is allowed'
+	authorize func(filePath string) error
+}
+
+func (h *streamHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	// cleaning of rooted path removes all '..' elements
+	filePath := filepath.Join(h.root, filepath.FromSlash(path.Clean("/"+req.URL.Path)))
+
+	mode := h.options.Mode
+	if value := req.URL.Query().Get("download"); value != "" {
+		if err := mode.Set(value); err != nil || mode != ModeZip && mode != ModeTarGz {
+			http.Error(w, fmt.Sprintf("unknown archive format: %q", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if mode == ModeZip || mode == ModeTarGz {
+		h.serveArchive(w, filePath, mode)
+		return
+	}
+
+	if _, isInfo := req.URL.Query()["info"]; isInfo {
+		h.serveInfo(w, filePath)
+		return
+	}
+
+	if pattern := req.URL.Query().Get("search"); pattern != "" {
+		h.serveSearch(w, req, filePath, pattern)
+		return
+	}
+
+	var offset int64
+	if value := req.URL.Query().Get("offset"); value != "" {
+		var err error
+		if offset, err = strconv.ParseInt(value, 10, 64); err != nil || offset < 0 {
+			http.Error(w, fmt.Sprintf("incorrect offset: %q", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if value := req.URL.Query().Get("line"); value != "" {
+		var ok bool
+		if offset, ok = h.lineOffset(w, filePath, value); !ok {
+			return
+		}
+	}
+
+	if hash := req.URL.Query().Get("resume_hash"); hash != "" {
+		var ok bool
+		if offset, ok = h.resumeOffset(w, filePath, offset, hash, req.URL.Query().Get("resume_length")); !ok {
+			return
+		}
+	}
+
+	var err error
+	switch mode {
+	case ModeRaw:
+		err = StreamRawData(filePath, offset, h.streamer, w, h.options.Timeout)
+	case ModeWebSocket:
+		err = StreamWebSocket(w, req, filePath, h.streamer, WebSocketOptions{InitialOffset: offset, Timeout: h.options.Timeout, Trailer: h.options.Trailer})
+	case ModeSSE:
+		err = StreamSSE(w, req, filePath, h.streamer, HTTPStreamOptions{InitialOffset: offset, Timeout: h.options.Timeout, Trailer: h.options.Trailer})
+	default:
+		err = StreamHTTP(w, req, filePath, h.streamer, HTTPStreamOptions{InitialOffset: offset, Timeout: h.options.Timeout, Trailer: h.options.Trailer})
+	}
+
+	if err != nil {
+		h.streamer.logger.Printf("File '%s' streaming error: %s", filePath, err.Error())
+	}
+}
+
+func (h *streamHandler) serveArchive(w http.ResponseWriter, pattern string, mode StreamMode) {
+	files, err := archivedFiles(h.root, pattern)
+	if err == ErrPathOutsideRoot {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil || len(files) == 0 {
+		http.Error(w, "No files to archive", http.StatusNotFound)
+		return
+	}
+
+	if h.authorize != nil {
+		for _, file := range files {
+			if err = h.authorize(file); err != nil {
+				http.Error(w, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+	}
+
+	if err = StreamArchive(w, h.root, files, mode); err != nil {
+		h.streamer.logger.Printf("Files '%s' archiving error: %s", pattern, err.Error())
+	}
+}
+
+func (h *streamHandler) serveInfo(w http.ResponseWriter, filePath string) {
+	stats, err := FileInfo(filePath, FileInfoOptions{Cache: h.lineCounts})
+	if err != nil {
+		http.Error(w, "Can't open file: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(stats); err != nil {
+		h.streamer.logger.Printf("File '%s' info error: %s", filePath, err.Error())
+	}
+}
+
+// Limits of 'search' request parameters: bigger values are clamped, so one request can't make Handler to hold huge
+// results in memory.
+const (
+	maxSearchMatches = 1000
+	maxSearchContext = 100
+)
+
+func (h *streamHandler) serveSearch(w http.ResponseWriter, req *http.Request, filePath, pattern string) {
+	var options SearchOptions
+	for name, value := range map[string]*int{"context": &options.Before, "max": &options.MaxMatches} {
+		if param := req.URL.Query().Get(name); param != "" {
+			var err error
+			if *value, err = strconv.Atoi(param); err != nil || *value < 0 {
+				http.Error(w, fmt.Sprintf("incorrect %s: %q", name, param), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+	if options.Before > maxSearchContext {
+		options.Before = maxSearchContext
+	}
+	if options.MaxMatches > maxSearchMatches {
+		options.MaxMatches = maxSearchMatches
+	}
+	options.After = options.Before
+
+	if param := req.URL.Query().Get("offset"); param != "" {
+		var err error
+		if options.Offset, err = strconv.ParseInt(param, 10, 64); err != nil || options.Offset < 0 {
+			http.Error(w, fmt.Sprintf("incorrect offset: %q", param), http.StatusBadRequest)
+			return
+		}
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		http.Error(w, "Incorrect search pattern: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	matches, err := SearchRegexp(filePath, re, options)
+	if err != nil {
+		http.Error(w, "Can't search file: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if matches == nil {
+		matches = []SearchMatch{} // '[]' instead of 'null'
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err = json.NewEncoder(w).Encode(matches); err != nil {
+		h.streamer.logger.Printf("File '%s' search error: %s", filePath, err.Error())
+	}
+}
+
+// lineOffset returns the offset of line number <value> (counting from 0), using Streamer's line index when the file
+// is indexed. Replies with an error and returns false when there is no such line.
+func (h *streamHandler) lineOffset(w http.ResponseWriter, filePath, value string) (int64, bool) {
+	line, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || line < 0 {
+		http.Error(w, fmt.Sprintf("incorrect line: %q", value), http.StatusBadRequest)
+		return 0, false
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.Error(w, "Can't open file for streaming: "+err.Error(), http.StatusNotFound)
+		return 0, false
+	}
+	defer file.Close()
+
+	index := h.streamer.LineIndex(filePath)
+	if index == nil {
+		index = NewLineIndex(1000)
+	}
+
+	offset, err := index.LineOffset(file, line)
+	switch err {
+	case nil:
+		return offset, true
+	case ErrLineOutOfRange:
+		http.Error(w, err.Error(), http.StatusRequestedRangeNotSatisfiable)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+
+	return 0, false
+}
+
+// resumeOffset finds the resume point of ResumeRequest sent in query parameters. Replies with an error and returns
+// false when there is no such point.
+func (h *streamHandler) resumeOffset(w http.ResponseWriter, filePath string, offset int64, hash, length string) (int64, bool) {
+	request := ResumeRequest{Type: ResumeType, Offset: offset, Hash: hash}
+
+	var err error
+	if request.Length, err = strconv.Atoi(length); err != nil {
+		http.Error(w, fmt.Sprintf("incorrect resume length: %q", length), http.StatusBadRequest)
+		return 0, false
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.Error(w, "Can't open file for streaming: "+err.Error(), http.StatusNotFound)
+		return 0, false
+	}
+	defer file.Close()
+
+	resumeOffset, err := FindResumeOffset(file, request, h.options.ResumeWindow)
+	switch err {
+	case nil:
+		return resumeOffset, true
+	case ErrInvalidResumeRequest:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case ErrResumePointNotFound:
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+
+	return 0, false
+}
+
+// ServerConfig is a configuration of file streaming HTTP server, shared by command line tools built on the package.
+type ServerConfig struct {
+	Addr       string // address to listen, ':4444' by default
+	Root       string // directory to stream files from, current directory by default
+	PathPrefix string // URL path prefix of streams, '/log-stream/' by default
+	HandlerOptions
+}
+
+// DefaultServerConfig returns the configuration used when no flags are given.
+func DefaultServerConfig() ServerConfig {
+	return ServerConfig{
+		Addr:           ":4444",
+		Root:           "./",
+		PathPrefix:     "/log-stream/",
+		HandlerOptions: HandlerOptions{Timeout: 2 * time.Second},
+	}
+}
+
+// RegisterFlags defines command line flags for config fields in <flags>, using current field values as defaults.
+func (c *ServerConfig) RegisterFlags(flags *flag.FlagSet) {
+	flags.StringVar(&c.Addr, "addr", c.Addr, "address to listen")
+	flags.StringVar(&c.Root, "root", c.Root, "directory to stream files from")
+	flags.StringVar(&c.PathPrefix, "prefix", c.PathPrefix, "URL path prefix of streams")
+	flags.Var(&c.Mode, "mode", "stream protocol: http, raw, websocket, sse, zip or tar.gz")
+	flags.DurationVar(&c.Timeout, "timeout", c.Timeout, "stop streaming after this period of file inactivity, 0 means never")
+}
+
+// Mux returns http.ServeMux that serves Handler at config's path prefix.
+func (c ServerConfig) Mux(streamer *Streamer) *http.ServeMux {
+	prefix := "/" + strings.Trim(c.PathPrefix, "/") + "/"
+	if prefix == "//" {
+		prefix = "/"
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(prefix, http.StripPrefix(prefix, Handler(streamer, c.Root, c.HandlerOptions)))
+
+	return mux
+}
@@ -0,0 +1,177 @@
+package file_streamer
+
+import (
+	"bufio"
+	"flag"
+	"github.com/gorilla/websocket"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestServer starts HTTP server configured with <config>, streaming files from a new temporary directory.
+func startTestServer(t *testing.T, config ServerConfig) (*httptest.Server, string) {
+	root, err := ioutil.TempDir("", "handler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	config.Root = root
+	server := httptest.NewServer(config.Mux(startTestStreamer(t)))
+	t.Cleanup(server.Close)
+
+	return server, root
+}
+
+func writeRootFile(t *testing.T, root, name, data string) {
+	if err := ioutil.WriteFile(filepath.Join(root, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readAtLeast reads from <r> until it gets <want> bytes, failing the test on mismatch.
+func readAtLeast(t *testing.T, r io.Reader, want string) {
+	t.Helper()
+
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(r, got); err != nil {
+		t.Fatalf("read %q: %v", got, err)
+	}
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestHandlerHTTP(t *testing.T) {
+	config := DefaultServerConfig()
+	config.Timeout = 0
+	server, root := startTestServer(t, config)
+	writeRootFile(t, root, "app.log", "first\n")
+
+	resp, err := http.Get(server.URL + "/log-stream/app.log?offset=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	readAtLeast(t, resp.Body, "rst\n")
+	appendToFile(t, filepath.Join(root, "app.log"), "second\n")
+	readAtLeast(t, resp.Body, "second\n")
+}
+
+func TestHandlerRejectsBadRequests(t *testing.T) {
+	server, root := startTestServer(t, DefaultServerConfig())
+	writeRootFile(t, root, "app.log", "data")
+
+	handler := Handler(startTestStreamer(t), filepath.Join(root, "sub"), HandlerOptions{})
+	for _, path := range []string{"/../app.log", "/sub/../../app.log"} {
+		// ServeMux would redirect such paths, so the handler is called directly
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = path
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("path '%s' got %d response, want %d", path, w.Code, http.StatusNotFound)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/log-stream/app.log?offset=-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("negative offset got %d response, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
+
+func TestHandlerWebSocket(t *testing.T) {
+	config := DefaultServerConfig()
+	config.Mode, config.Timeout = ModeWebSocket, 0
+	server, root := startTestServer(t, config)
+	writeRootFile(t, root, "app.log", "first\n")
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/log-stream/app.log", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	expectMessage := func(want string) {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msgType != websocket.TextMessage || string(data) != want {
+			t.Fatalf("got message %q of type %d, want text %q", data, msgType, want)
+		}
+	}
+
+	expectMessage("first\n")
+	appendToFile(t, filepath.Join(root, "app.log"), "second\n")
+	expectMessage("second\n")
+}
+
+func TestHandlerSSE(t *testing.T) {
+	config := DefaultServerConfig()
+	config.Mode, config.Timeout = ModeSSE, 0
+	server, root := startTestServer(t, config)
+	writeRootFile(t, root, "app.log", "first\r\nsecond\nthi")
+
+	resp, err := http.Get(server.URL + "/log-stream/app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("got Content-Type '%s', want text/event-stream", contentType)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	readAtLeast(t, body, "id: 7\ndata: first\n\nid: 14\ndata: second\n\n")
+
+	// the incomplete line is sent when it is finished
+	appendToFile(t, filepath.Join(root, "app.log"), "rd\n")
+	readAtLeast(t, body, "id: 20\ndata: third\n\n")
+
+	// reconnecting EventSource continues after the last event it got
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/log-stream/app.log", nil)
+	req.Header.Set("Last-Event-ID", "7")
+	resumed, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resumed.Body.Close()
+
+	readAtLeast(t, resumed.Body, "id: 14\ndata: second\n\nid: 20\ndata: third\n\n")
+}
+
+func TestServerConfigFlags(t *testing.T) {
+	config := DefaultServerConfig()
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	config.RegisterFlags(flags)
+
+	if err := flags.Parse([]string{"-addr", ":8080", "-mode", "sse", "-timeout", "5s"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Addr != ":8080" || config.Mode != ModeSSE || config.Timeout != 5*time.Second || config.Root != "./" {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	flags = flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(ioutil.Discard)
+	config.RegisterFlags(flags)
+	if err := flags.Parse([]string{"-mode", "ftp"}); err == nil {
+		t.Error("unknown mode was accepted")
+	}
+}
@@ -0,0 +1,146 @@
+package file_streamer
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Stream protocol versions. Version 0 is the raw stream of file data without any handshake: clients that don't know
+// about handshake get it, so they degrade gracefully instead of breaking when the server upgrades.
+const (
+	ProtocolRaw     = 0
+	ProtocolFramed  = 1
+	ProtocolVersion = ProtocolFramed // the latest version supported by this package
+)
+
+// Stream capabilities
+const (
+	CompressionNone = "none"
+
+	FramingRaw  = "raw"
+	FramingJSON = "json" // chunks encoded with JSONEncoder
+)
+
+// ErrIncompatibleProtocol is returned by Negotiate() when client and server have no protocol version in common.
+var ErrIncompatibleProtocol = errors.New("no common stream protocol version")
+
+// HandshakeType is the value of Hello.Type, which distinguishes handshake messages from other messages of a
+// transport.
+const HandshakeType = "hello"
+
+// Hello is a handshake message exchanged by client and server before streaming: each side announces protocol
+// versions and capabilities it supports, in the order of preference. The message is transport-agnostic: send it
+// as a WebSocket text message, an SSE event or a JSON line of a raw stream.
+type Hello struct {
+	Type       string `json:"type"`
+	Version    int    `json:"version"`     // the latest supported version
+	MinVersion int    `json:"min_version"` // the oldest supported version
+
+	Compression []string `json:"compression,omitempty"`
+	Framing     []string `json:"framing,omitempty"`
+
+	HeartbeatInterval time.Duration `json:"heartbeat_ms"` // 0 means 'no heartbeats'
+}
+
+// MarshalJSON encodes HeartbeatInterval in milliseconds: browsers don't know Go durations.
+func (h Hello) MarshalJSON() ([]byte, error) {
+	type plain Hello
+	p := plain(h)
+	p.Type = HandshakeType
+	p.HeartbeatInterval = h.HeartbeatInterval / time.Millisecond
+
+	return json.Marshal(p)
+}
+
+// UnmarshalJSON decodes HeartbeatInterval from milliseconds.
+func (h *Hello) UnmarshalJSON(data []byte) error {
+	type plain Hello
+	var p plain
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+
+	*h = Hello(p)
+	h.HeartbeatInterval *= time.Millisecond
+	return nil
+}
+
+// ServerHello returns Hello describing everything this package supports. Remove capabilities the server does not
+// want to offer before sending it.
+func ServerHello(heartbeatInterval time.Duration) Hello {
+	return Hello{
+		Type:              HandshakeType,
+		Version:           ProtocolVersion,
+		MinVersion:        ProtocolRaw,
+		Compression:       []string{CompressionNone},
+		Framing:           []string{FramingRaw, FramingJSON},
+		HeartbeatInterval: heartbeatInterval,
+	}
+}
+
+// Agreement is the result of handshake: protocol version and capabilities both sides support.
+type Agreement struct {
+	Version           int
+	Compression       string
+	Framing           string
+	HeartbeatInterval time.Duration
+}
+
+// Hello returns the handshake reply describing <a>: the server sends it back to the client, so both sides know what
+// was agreed on.
+func (a Agreement) Hello() Hello {
+	return Hello{
+		Type:              HandshakeType,
+		Version:           a.Version,
+		MinVersion:        a.Version,
+		Compression:       []string{a.Compression},
+		Framing:           []string{a.Framing},
+		HeartbeatInterval: a.HeartbeatInterval,
+	}
+}
+
+// RawAgreement is the agreement for clients that sent no Hello: raw data, no compression and no heartbeats.
+var RawAgreement = Agreement{Version: ProtocolRaw, Compression: CompressionNone, Framing: FramingRaw}
+
+// Negotiate chooses the latest protocol version both sides support, and the capabilities the server prefers most
+// among supported by client. Missing capabilities lists mean 'none' compression and 'raw' framing. The heartbeat
+// interval is the shortest one of non-zero intervals: heartbeats must come often enough for both sides.
+//
+// Returns ErrIncompatibleProtocol when version ranges of client and server don't intersect.
+func Negotiate(server, client Hello) (Agreement, error) {
+	version := server.Version
+	if client.Version < version {
+		version = client.Version
+	}
+
+	if version < server.MinVersion || version < client.MinVersion {
+		return Agreement{}, ErrIncompatibleProtocol
+	}
+
+	agreement := Agreement{
+		Version:           version,
+		Compression:       preferred(server.Compression, client.Compression, CompressionNone),
+		Framing:           preferred(server.Framing, client.Framing, FramingRaw),
+		HeartbeatInterval: server.HeartbeatInterval,
+	}
+
+	if agreement.HeartbeatInterval == 0 || client.HeartbeatInterval != 0 && client.HeartbeatInterval < agreement.HeartbeatInterval {
+		agreement.HeartbeatInterval = client.HeartbeatInterval
+	}
+
+	return agreement, nil
+}
+
+// preferred returns the first of <server> values supported by <client>, <fallback> when there are none.
+func preferred(server, client []string, fallback string) string {
+	for _, value := range server {
+		for _, supported := range client {
+			if value == supported {
+				return value
+			}
+		}
+	}
+
+	return fallback
+}
@@ -0,0 +1,71 @@
+package file_streamer
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNegotiate(t *testing.T) {
+	server := ServerHello(30 * time.Second)
+
+	cases := []struct {
+		name   string
+		client Hello
+		want   Agreement
+	}{
+		{
+			"old client",
+			Hello{Version: ProtocolRaw},
+			Agreement{Version: ProtocolRaw, Compression: CompressionNone, Framing: FramingRaw, HeartbeatInterval: 30 * time.Second},
+		},
+		{
+			"newer client",
+			Hello{Version: ProtocolVersion + 1, Compression: []string{"zstd"}, Framing: []string{FramingJSON}},
+			Agreement{Version: ProtocolVersion, Compression: CompressionNone, Framing: FramingJSON, HeartbeatInterval: 30 * time.Second},
+		},
+		{
+			"client with shorter heartbeat",
+			Hello{Version: ProtocolVersion, Compression: []string{CompressionNone}, HeartbeatInterval: 5 * time.Second},
+			Agreement{Version: ProtocolVersion, Compression: CompressionNone, Framing: FramingRaw, HeartbeatInterval: 5 * time.Second},
+		},
+	}
+
+	for _, c := range cases {
+		got, err := Negotiate(server, c.client)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if got != c.want {
+			t.Errorf("%s: agreed on %+v, want %+v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestNegotiateIncompatible(t *testing.T) {
+	server := ServerHello(0)
+	client := Hello{Version: ProtocolVersion + 2, MinVersion: ProtocolVersion + 1}
+
+	if _, err := Negotiate(server, client); err != ErrIncompatibleProtocol {
+		t.Errorf("got %v, want %v", err, ErrIncompatibleProtocol)
+	}
+}
+
+func TestHelloJSON(t *testing.T) {
+	data, err := json.Marshal(Hello{Version: 1, HeartbeatInterval: 1500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := `{"type":"hello","version":1,"min_version":0,"heartbeat_ms":1500}`; string(data) != want {
+		t.Errorf("encoded %s, want %s", data, want)
+	}
+
+	var decoded Hello
+	if err = json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Type != HandshakeType || decoded.HeartbeatInterval != 1500*time.Millisecond {
+		t.Errorf("decoded %+v", decoded)
+	}
+}
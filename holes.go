@@ -0,0 +1,81 @@
+package file_streamer
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// ErrSkipHolesUnsupported is returned by Listener.SetSkipHoles() on platforms that can't find holes in sparse files.
+var ErrSkipHolesUnsupported = errors.New("skipping holes of sparse files is not supported on this platform")
+
+// SetSkipHoles makes Streamer to skip holes of sparse files instead of streaming gigabytes of zeros. Data regions
+// are streamed as is, so the holes inside of filesystem blocks that hold data are still streamed.
+//
+// Keep in mind the amount of streamed data reported by audit events still counts the skipped holes.
+//
+// Should be called before passing Listener to Streamer.StreamTo(). Returns ErrSkipHolesUnsupported on platforms other
+// than Linux.
+func (bs *Listener) SetSkipHoles(enabled bool) error {
+	if !skipHolesSupported {
+		return ErrSkipHolesUnsupported
+	}
+
+	bs.mu.Lock()
+	bs.skipHoles = enabled
+	bs.mu.Unlock()
+
+	return nil
+}
+
+// copyDataRegions calls <copyRegion> for each data region of <file> from the current position up to the end of file,
+// skipping holes between them. <copyRegion> gets a reader limited by region size.
+func copyDataRegions(file *os.File, copyRegion func(src io.Reader) error) error {
+	for {
+		offset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		dataStart, holeStart, err := nextDataRegion(file, offset)
+		if err == errNoMoreData {
+			return skipTrailingHole(file, offset)
+		}
+		if err != nil {
+			// filesystem can't find holes: read the rest of file as a single region
+			dataStart, holeStart = offset, math.MaxInt64
+		}
+
+		if _, err = file.Seek(dataStart, io.SeekStart); err != nil {
+			return err
+		}
+
+		if err = copyRegion(io.LimitReader(file, holeStart-dataStart)); err != nil {
+			return err
+		}
+
+		newOffset, err := file.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		if newOffset < holeStart {
+			return nil // the end of file was reached before the end of region
+		}
+	}
+}
+
+// skipTrailingHole moves the position of <file> from <offset> to the end of file, when file ends with a hole.
+func skipTrailingHole(file *os.File, offset int64) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() > offset {
+		_, err = file.Seek(info.Size(), io.SeekStart)
+	}
+
+	return err
+}
@@ -0,0 +1,36 @@
+package file_streamer
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const skipHolesSupported = true
+
+// lseek(2) whence values, not defined by syscall package.
+const (
+	seekData = 3
+	seekHole = 4
+)
+
+var errNoMoreData = errors.New("no data after offset")
+
+// nextDataRegion returns the bounds of the first data region of <file> that starts at or after <offset>. Returns
+// errNoMoreData when there is no data after <offset>.
+func nextDataRegion(file *os.File, offset int64) (dataStart, holeStart int64, err error) {
+	dataStart, err = file.Seek(offset, seekData)
+	if errors.Is(err, syscall.ENXIO) {
+		return 0, 0, errNoMoreData
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	holeStart, err = file.Seek(dataStart, seekHole)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return dataStart, holeStart, nil
+}
@@ -0,0 +1,76 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+// createSparseFile creates a file with <head> and <tail> separated by a hole of <holeSize> bytes.
+func createSparseFile(t *testing.T, head string, holeSize int64, tail string) string {
+	name := createTestFile(t, head)
+
+	f, err := os.OpenFile(name, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteAt([]byte(tail), int64(len(head))+holeSize); err != nil {
+		t.Fatal(err)
+	}
+
+	return name
+}
+
+func TestSkipHoles(t *testing.T) {
+	const holeSize = 64 << 20
+
+	s := startTestStreamer(t)
+	name := createSparseFile(t, "head", holeSize, "tail")
+
+	var out bytes.Buffer
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(&out))
+	if err := listener.SetSkipHoles(true); err != nil {
+		t.Fatal(err)
+	}
+	catFile(t, s, listener)
+
+	// data regions are aligned to filesystem blocks, so some zeros around the data are still streamed
+	if out.Len() >= holeSize {
+		t.Fatalf("streamed %d bytes, the hole was not skipped", out.Len())
+	}
+
+	data := out.String()
+	if !strings.HasPrefix(data, "head") || !strings.HasSuffix(data, "tail") {
+		t.Fatal("streamed data does not start with head and end with tail")
+	}
+	if strings.Trim(data[4:len(data)-4], "\x00") != "" {
+		t.Error("unexpected data between head and tail")
+	}
+}
+
+func TestSkipHolesTrailingHole(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "data")
+	if err := os.Truncate(name, 64<<20); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	file := openTestFile(t, name)
+	listener := NewListener(file, bufio.NewWriter(&out))
+	listener.SetSkipHoles(true)
+	catFile(t, s, listener)
+
+	if !strings.HasPrefix(out.String(), "data") || out.Len() >= 64<<20 {
+		t.Errorf("streamed %d bytes, want data without the trailing hole", out.Len())
+	}
+
+	if offset, _ := file.Seek(0, io.SeekCurrent); offset != 64<<20 {
+		t.Errorf("file position is %d after streaming, want the end of file", offset)
+	}
+}
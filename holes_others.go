@@ -0,0 +1,18 @@
+//go:build !linux
+// +build !linux
+
+package file_streamer
+
+import (
+	"errors"
+	"os"
+)
+
+const skipHolesSupported = false
+
+var errNoMoreData = errors.New("no data after offset")
+
+// nextDataRegion is never called on platforms without holes detection: Listener.SetSkipHoles() refuses to enable it.
+func nextDataRegion(file *os.File, offset int64) (dataStart, holeStart int64, err error) {
+	return 0, 0, ErrSkipHolesUnsupported
+}
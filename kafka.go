@@ -0,0 +1,108 @@
+package file_streamer
+
+import (
+	"path/filepath"
+	"sync"
+)
+
+// KafkaMessage is a message published by KafkaWriter.
+type KafkaMessage struct {
+	Topic string
+	Key   []byte // partition key, selected by KafkaWriterOptions.Key
+	Value []byte
+}
+
+// KafkaProducer publishes messages to Kafka. Wrap the client library you use (sarama, confluent-kafka-go, kafka-go)
+// to implement it: the package does not depend on any of them.
+//
+// Produce() must not retain message data after it returns: the writer reuses its buffers.
+type KafkaProducer interface {
+	Produce(message KafkaMessage) error
+}
+
+// KafkaProducerFunc is an adapter to allow the use of ordinary functions as Kafka producers.
+type KafkaProducerFunc func(message KafkaMessage) error
+
+// Produce calls f(message).
+func (f KafkaProducerFunc) Produce(message KafkaMessage) error {
+	return f(message)
+}
+
+// KafkaKeyFunc selects partition key for messages with data of <file>.
+type KafkaKeyFunc func(file string) []byte
+
+// KafkaKeyByPath uses full path of the file as partition key, keeping all data of one file in one partition.
+func KafkaKeyByPath(file string) []byte {
+	return []byte(file)
+}
+
+// KafkaKeyByName uses base name of the file as partition key, keeping the same files of different hosts or
+// directories in one partition.
+func KafkaKeyByName(file string) []byte {
+	return []byte(filepath.Base(file))
+}
+
+// KafkaWriterOptions configures KafkaWriter.
+type KafkaWriterOptions struct {
+	Key     KafkaKeyFunc // KafkaKeyByPath by default
+	PerLine bool         // publish each line as a separate message instead of each flushed chunk
+}
+
+// KafkaWriter publishes data written to it to a Kafka topic.
+//
+// Each Write() call is published as a single message, so with a Listener (which flushes its buffered writer after
+// each portion of file data) each flushed chunk becomes a message. In PerLine mode each line becomes a message
+// instead; an incomplete line is held until the rest of it is written or Flush() is called.
+type KafkaWriter struct {
+	producer KafkaProducer
+	topic    string
+	key      []byte
+	perLine  bool
+
+	mu    sync.Mutex
+	lines lineSplitter
+}
+
+// NewKafkaWriter creates a writer that publishes data of <file> to <topic> through <producer>.
+func NewKafkaWriter(producer KafkaProducer, topic, file string, options KafkaWriterOptions) *KafkaWriter {
+	if options.Key == nil {
+		options.Key = KafkaKeyByPath
+	}
+
+	return &KafkaWriter{
+		producer: producer,
+		topic:    topic,
+		key:      options.Key(file),
+		perLine:  options.PerLine,
+	}
+}
+
+func (k *KafkaWriter) Write(p []byte) (int, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var err error
+	if k.perLine {
+		err = k.lines.split(p, k.produce)
+	} else {
+		err = k.produce(p)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush publishes the incomplete line held in PerLine mode.
+func (k *KafkaWriter) Flush() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	return k.lines.flush(k.produce)
+}
+
+func (k *KafkaWriter) produce(value []byte) error {
+	return k.producer.Produce(KafkaMessage{Topic: k.topic, Key: k.key, Value: value})
+}
@@ -0,0 +1,69 @@
+package file_streamer
+
+import (
+	"bufio"
+	"errors"
+	"testing"
+)
+
+type kafkaRecorder struct {
+	messages []KafkaMessage
+}
+
+func (r *kafkaRecorder) Produce(message KafkaMessage) error {
+	message.Value = append([]byte(nil), message.Value...)
+	r.messages = append(r.messages, message)
+	return nil
+}
+
+func TestKafkaWriterPerLine(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "first\nsecond\nthird")
+
+	producer := &kafkaRecorder{}
+	writer := NewKafkaWriter(producer, "logs", name, KafkaWriterOptions{Key: KafkaKeyByName, PerLine: true})
+	catFile(t, s, NewListener(openTestFile(t, name), bufio.NewWriter(writer)))
+
+	if len(producer.messages) != 2 {
+		t.Fatalf("published %d messages before flush, want 2", len(producer.messages))
+	}
+
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, want := range []string{"first", "second", "third"} {
+		message := producer.messages[i]
+		if message.Topic != "logs" || string(message.Value) != want {
+			t.Errorf("message %d is %s: %q, want logs: %q", i, message.Topic, message.Value, want)
+		}
+		if string(message.Key) != string(KafkaKeyByName(name)) {
+			t.Errorf("message %d key is %q", i, message.Key)
+		}
+	}
+}
+
+func TestKafkaWriterPerChunk(t *testing.T) {
+	producer := &kafkaRecorder{}
+	writer := NewKafkaWriter(producer, "logs", "/var/log/app.log", KafkaWriterOptions{})
+
+	writer.Write([]byte("a\nb"))
+	writer.Write([]byte("c\n"))
+
+	if len(producer.messages) != 2 || string(producer.messages[0].Value) != "a\nb" {
+		t.Fatalf("unexpected messages %+v", producer.messages)
+	}
+	if string(producer.messages[0].Key) != "/var/log/app.log" {
+		t.Errorf("key %q, want the file path", producer.messages[0].Key)
+	}
+}
+
+func TestKafkaWriterError(t *testing.T) {
+	failure := errors.New("broker is down")
+	writer := NewKafkaWriter(KafkaProducerFunc(func(KafkaMessage) error { return failure }), "logs", "app.log",
+		KafkaWriterOptions{})
+
+	if n, err := writer.Write([]byte("data")); n != 0 || err != failure {
+		t.Errorf("Write() returned %d, %v, want 0, %v", n, err, failure)
+	}
+}
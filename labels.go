@@ -0,0 +1,68 @@
+package file_streamer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels are arbitrary key-value pairs attributing a stream to its consumer: client IP, user, request ID and so on.
+type Labels map[string]string
+
+// String formats labels as '{key1=value1 key2=value2}' sorted by keys.
+func (l Labels) String() string {
+	keys := make([]string, 0, len(l))
+	for key := range l {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + l[key]
+	}
+
+	return "{" + strings.Join(pairs, " ") + "}"
+}
+
+func (l Labels) clone() Labels {
+	if len(l) == 0 {
+		return nil
+	}
+
+	result := make(Labels, len(l))
+	for key, value := range l {
+		result[key] = value
+	}
+
+	return result
+}
+
+// SetLabels attaches <labels> to Listener. Streamer includes them in its log lines about the stream, in audit events,
+// in tracing span attributes (as 'label.<key>') and in ActiveStreams() output, so operators can attribute streams to
+// users. <labels> are copied.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetLabels(labels Labels) {
+	bs.mu.Lock()
+	bs.labels = labels.clone()
+	bs.mu.Unlock()
+}
+
+// Labels returns a copy of labels attached to Listener.
+func (bs *Listener) Labels() Labels {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	return bs.labels.clone()
+}
+
+// logf logs a message about <listener>'s stream, appending its labels.
+func (s *Streamer) logf(listener *Listener, format string, v ...interface{}) {
+	message := fmt.Sprintf(format, v...)
+	if labels := listener.Labels(); len(labels) != 0 {
+		message += " " + labels.String()
+	}
+
+	s.logger.Print(message)
+}
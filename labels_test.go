@@ -0,0 +1,51 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestListenerLabels(t *testing.T) {
+	var logs syncBuffer
+	s := New(log.New(&logs, "", 0))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	var events []AuditEvent
+	s.SetAuditSink(AuditSinkFunc(func(event AuditEvent) { events = append(events, event) }))
+
+	name := createTestFile(t, "data")
+	labels := Labels{"user": "alice", "request_id": "42"}
+
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(ioutil.Discard))
+	listener.SetLabels(labels)
+	labels["user"] = "bob" // labels are copied
+
+	result := make(chan error, 1)
+	go func() { result <- s.StreamTo(listener, 0) }()
+	waitSubscribed(t, s, listener)
+
+	if info := s.ActiveStreams(); len(info) != 1 || info[0].Labels["user"] != "alice" {
+		t.Errorf("unexpected active streams %+v", info)
+	}
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range events {
+		if event.Labels["user"] != "alice" || event.Labels["request_id"] != "42" {
+			t.Errorf("unexpected event labels %v", event.Labels)
+		}
+	}
+
+	if !strings.Contains(logs.String(), "file {request_id=42 user=alice}") {
+		t.Errorf("labels are missing in log:\n%s", logs.String())
+	}
+}
@@ -0,0 +1,94 @@
+package file_streamer
+
+import "sync"
+
+// readLimiter limits the number of concurrent file reads.
+//
+// Waiting readers are served by their priority (see Listener.SetPriority()): a reader gets its turn only when there
+// are no waiting readers of higher priority. Readers of the same priority are grouped by file and served in
+// round-robin order across files, so a file with lots of listeners can't starve listeners of other files: each file
+// gets its turn before any other file gets the next one.
+type readLimiter struct {
+	mu sync.Mutex
+
+	limit  int
+	active int
+
+	queues [priorities]readQueue // indexed by priority - PriorityLow
+}
+
+// readQueue holds waiting readers of one priority.
+type readQueue struct {
+	waiting map[string][]chan empty // per-file queue of readers waiting for their turn
+	order   []string                // files with waiting readers in the order of their turns
+}
+
+func newReadLimiter(limit int) *readLimiter {
+	l := &readLimiter{limit: limit}
+	for i := range l.queues {
+		l.queues[i].waiting = make(map[string][]chan empty)
+	}
+
+	return l
+}
+
+// acquire blocks until reading from <file> with <priority> is allowed. nil limiter does not limit anything.
+func (l *readLimiter) acquire(file string, priority Priority) {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	if l.active < l.limit {
+		l.active++
+		l.mu.Unlock()
+		return
+	}
+
+	queue := &l.queues[priority.index()]
+
+	turn := make(chan empty)
+	if len(queue.waiting[file]) == 0 {
+		queue.order = append(queue.order, file)
+	}
+	queue.waiting[file] = append(queue.waiting[file], turn)
+	l.mu.Unlock()
+
+	<-turn // active counter is not decremented by release() when it passes the turn to us
+}
+
+// release finishes the read and passes the turn to the next waiting reader of the highest priority, if any.
+func (l *readLimiter) release() {
+	if l == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for i := len(l.queues) - 1; i >= 0; i-- {
+		if len(l.queues[i].order) > 0 {
+			close(l.queues[i].next())
+			return
+		}
+	}
+
+	l.active--
+}
+
+// next removes the next reader from the queue and returns its turn channel. The queue must not be empty.
+func (q *readQueue) next() chan empty {
+	file := q.order[0]
+	q.order = q.order[1:]
+
+	queue := q.waiting[file]
+	turn := queue[0]
+	if len(queue) > 1 {
+		q.waiting[file] = queue[1:]
+		q.order = append(q.order, file) // the rest of file's readers wait for the next round
+	} else {
+		delete(q.waiting, file)
+	}
+
+	return turn
+}
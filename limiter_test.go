@@ -0,0 +1,92 @@
+package file_streamer
+
+import (
+	"testing"
+	"time"
+)
+
+// waitQueued waits until <n> readers are waiting for their turn in limiter.
+func waitQueued(l *readLimiter, n int) {
+	for {
+		l.mu.Lock()
+		queued := 0
+		for _, priorityQueue := range l.queues {
+			for _, queue := range priorityQueue.waiting {
+				queued += len(queue)
+			}
+		}
+		l.mu.Unlock()
+
+		if queued == n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReadLimiterRoundRobin(t *testing.T) {
+	l := newReadLimiter(1)
+	l.acquire("busy", PriorityNormal) // occupy the only slot
+
+	// hot file gets 3 readers in the queue before the cold one
+	served := make(chan string, 4)
+	for i, file := range []string{"hot", "hot", "hot", "cold"} {
+		go func(file string) {
+			l.acquire(file, PriorityNormal)
+			served <- file
+		}(file)
+		waitQueued(l, i+1)
+	}
+
+	var order []string
+	for i := 0; i < 4; i++ {
+		l.release()
+		order = append(order, <-served)
+	}
+
+	want := []string{"hot", "cold", "hot", "hot"}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("served in order %v, want %v", order, want)
+		}
+	}
+
+	l.release()
+	if l.active != 0 {
+		t.Errorf("%d reads are still active after all releases", l.active)
+	}
+}
+
+func TestReadLimiterNil(t *testing.T) {
+	var l *readLimiter
+	l.acquire("file", PriorityHigh)
+	l.release()
+}
+
+func TestReadLimiterPriorities(t *testing.T) {
+	l := newReadLimiter(1)
+	l.acquire("busy", PriorityNormal)
+
+	// bulk export is queued first, the incident-response tail is served first anyway
+	served := make(chan Priority, 3)
+	for i, priority := range []Priority{PriorityLow, PriorityNormal, PriorityHigh} {
+		go func(priority Priority) {
+			l.acquire("file", priority)
+			served <- priority
+		}(priority)
+		waitQueued(l, i+1)
+	}
+
+	var order []Priority
+	for i := 0; i < 3; i++ {
+		l.release()
+		order = append(order, <-served)
+	}
+
+	want := []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("served in order %v, want %v", order, want)
+		}
+	}
+}
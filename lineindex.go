@@ -0,0 +1,217 @@
+package file_streamer
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"sync"
+)
+
+// ErrLineOutOfRange is returned by LineIndex.LineOffset() for lines beyond the end of file.
+var ErrLineOutOfRange = errors.New("line is beyond the end of file")
+
+// LineIndex is a sparse index of line numbers to byte offsets of a file: it remembers the offset of every Nth line,
+// so 'jump to line 1,200,000' scans N lines at most instead of the whole file. Lines are numbered from 0.
+//
+// The index is updated incrementally: Update() scans only the data appended since the previous call. It is safe for
+// concurrent use.
+type LineIndex struct {
+	mu sync.Mutex
+
+	every   int64
+	info    os.FileInfo // the indexed file, to find out it was replaced
+	offsets []int64     // offsets[i] is the offset of line i*every
+	lines   int64       // the number of line feeds in indexed data
+	size    int64       // the amount of indexed data
+	buf     []byte
+}
+
+// NewLineIndex creates an empty index that remembers offset of each <every> line.
+func NewLineIndex(every int) *LineIndex {
+	if every <= 0 {
+		every = 1
+	}
+
+	return &LineIndex{every: int64(every), offsets: []int64{0}}
+}
+
+func (x *LineIndex) reset(info os.FileInfo) {
+	x.info = info
+	x.offsets = x.offsets[:1]
+	x.lines = 0
+	x.size = 0
+}
+
+// Update indexes data of <file> up to <end> offset, starting where the previous call stopped. The index is rebuilt
+// from scratch when <file> was truncated or is not the file indexed before.
+func (x *LineIndex) Update(file *os.File, end int64) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	if x.info == nil || !os.SameFile(x.info, info) || info.Size() < x.size {
+		x.reset(info)
+	}
+
+	if x.buf == nil && x.size < end {
+		x.buf = make([]byte, 64*1024)
+	}
+
+	for x.size < end {
+		size := int64(len(x.buf))
+		if end-x.size < size {
+			size = end - x.size
+		}
+
+		n, err := file.ReadAt(x.buf[:size], x.size)
+		x.add(x.buf[:n])
+
+		if err == io.EOF {
+			return nil // the file is shorter than <end>
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *LineIndex) add(data []byte) {
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			x.size += int64(len(data))
+			return
+		}
+
+		x.size += int64(i + 1)
+		data = data[i+1:]
+
+		if x.lines++; x.lines%x.every == 0 {
+			x.offsets = append(x.offsets, x.size)
+		}
+	}
+}
+
+// Lines returns the number of line feeds in indexed data.
+func (x *LineIndex) Lines() int64 {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	return x.lines
+}
+
+// Lookup returns the offset of the closest indexed line at or before <line>, and the number of that line.
+func (x *LineIndex) Lookup(line int64) (offset, indexedLine int64) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	i := line / x.every
+	if i >= int64(len(x.offsets)) {
+		i = int64(len(x.offsets)) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+
+	return x.offsets[i], i * x.every
+}
+
+// LineOffset returns the offset of the beginning of <line> in <file>: it brings the index up to date, and scans the
+// file from the closest indexed line. Returns ErrLineOutOfRange when the file has less lines.
+func (x *LineIndex) LineOffset(file *os.File, line int64) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = x.Update(file, info.Size()); err != nil {
+		return 0, err
+	}
+
+	if line < 0 {
+		return 0, ErrLineOutOfRange
+	}
+	offset, current := x.Lookup(line)
+
+	buf := make([]byte, 64*1024)
+	for current < line {
+		n, err := file.ReadAt(buf, offset)
+		data := buf[:n]
+
+		for current < line {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				offset += int64(len(data))
+				break
+			}
+
+			offset += int64(i + 1)
+			data = data[i+1:]
+			current++
+		}
+
+		if current < line && err != nil {
+			if err == io.EOF {
+				return 0, ErrLineOutOfRange
+			}
+			return 0, err
+		}
+	}
+
+	return offset, nil
+}
+
+// SetLineIndexInterval makes Streamer maintain sparse line index (see LineIndex) of each streamed file, remembering
+// offset of each <every> line. Indexes are updated with the data being streamed, so Streamer.LineIndex() gives seeks
+// to a line without rescanning the file. The first stream of a file indexes its data from the beginning of file up
+// to the stream start offset. Zero <every> (the default) disables indexing.
+//
+// Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetLineIndexInterval(every int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.lineIndexEvery = every
+	return nil
+}
+
+// LineIndex returns line index of the file at <path>, nil when the file was never streamed or indexing is disabled
+// (see Streamer.SetLineIndexInterval()).
+func (s *Streamer) LineIndex(path string) *LineIndex {
+	path = s.normalizePath(path)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lineIndexes[path]
+}
+
+// lineIndex returns line index for the file of <listener>, creating it when needed. Returns nil when indexing is
+// disabled.
+func (s *Streamer) lineIndex(listener *Listener) *LineIndex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.lineIndexEvery <= 0 {
+		return nil
+	}
+
+	index, exists := s.lineIndexes[listener.watchPath]
+	if !exists {
+		index = NewLineIndex(s.lineIndexEvery)
+		s.lineIndexes[listener.watchPath] = index
+	}
+
+	return index
+}
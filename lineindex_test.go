@@ -0,0 +1,100 @@
+package file_streamer
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLineIndex(t *testing.T) {
+	var data strings.Builder
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&data, "line %d\n", i)
+	}
+	name := createTestFile(t, data.String())
+	file := openTestFile(t, name)
+
+	index := NewLineIndex(100)
+	for _, line := range []int64{0, 1, 99, 100, 555, 999, 1000} {
+		want := int64(data.Len()) // line 1000 is the empty line at the end of file
+		if line < 1000 {
+			want = int64(strings.Index(data.String(), fmt.Sprintf("line %d\n", line)))
+		}
+
+		if offset, err := index.LineOffset(file, line); err != nil || offset != want {
+			t.Errorf("line %d: got offset %d (%v), want %d", line, offset, err, want)
+		}
+	}
+
+	if offset, indexed := index.Lookup(555); indexed != 500 || offset != int64(strings.Index(data.String(), "line 500\n")) {
+		t.Errorf("lookup returned line %d at %d, want line 500", indexed, offset)
+	}
+
+	if _, err := index.LineOffset(file, 1001); err != ErrLineOutOfRange {
+		t.Errorf("seek beyond the end returned %v, want %v", err, ErrLineOutOfRange)
+	}
+
+	// truncation rebuilds the index
+	if err := os.Truncate(name, 7); err != nil {
+		t.Fatal(err)
+	}
+	if offset, err := index.LineOffset(file, 1); err != nil || offset != 7 || index.Lines() != 1 {
+		t.Errorf("got offset %d (%v) and %d lines after truncation", offset, err, index.Lines())
+	}
+}
+
+func TestStreamerLineIndex(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	if err := s.SetLineIndexInterval(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	if err := s.SetLineIndexInterval(10); err != ErrRunning {
+		t.Errorf("interval change of running streamer returned %v, want %v", err, ErrRunning)
+	}
+
+	name := createTestFile(t, "a\nb\nc\nd\ne\n")
+	if s.LineIndex(name) != nil {
+		t.Error("file is indexed before streaming")
+	}
+
+	catFile(t, s, NewListener(openTestFile(t, name), bufio.NewWriter(ioutil.Discard)))
+
+	index := s.LineIndex(name)
+	if index == nil || index.Lines() != 5 {
+		t.Fatalf("streamed file index is %+v", index)
+	}
+	if offset, line := index.Lookup(5); offset != 8 || line != 4 {
+		t.Errorf("lookup returned line %d at %d, want line 4 at 8", line, offset)
+	}
+}
+
+func TestHandlerLine(t *testing.T) {
+	server, root := startTestServer(t, DefaultServerConfig())
+	writeRootFile(t, root, "app.log", "one\ntwo\nthree\n")
+
+	resp, err := http.Get(server.URL + "/log-stream/app.log?line=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	readAtLeast(t, resp.Body, "three\n")
+
+	resp, err = http.Get(server.URL + "/log-stream/app.log?line=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Errorf("got status %d for missing line, want %d", resp.StatusCode, http.StatusRequestedRangeNotSatisfiable)
+	}
+}
@@ -0,0 +1,45 @@
+package file_streamer
+
+import "bytes"
+
+// lineSplitter cuts written data into complete lines, holding the incomplete last line until the rest of it is
+// written.
+type lineSplitter struct {
+	partial []byte
+}
+
+// split calls <emit> for each complete line in <p> (with data left from previous calls prepended). Lines are passed
+// without the trailing '\n' and are valid until <emit> returns.
+func (l *lineSplitter) split(p []byte, emit func(line []byte) error) error {
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			l.partial = append(l.partial, p...)
+			return nil
+		}
+
+		line := p[:i]
+		if len(l.partial) != 0 {
+			line = append(l.partial, line...)
+		}
+
+		err := emit(bytes.TrimSuffix(line, []byte{'\r'}))
+		l.partial = l.partial[:0]
+		if err != nil {
+			return err
+		}
+
+		p = p[i+1:]
+	}
+}
+
+// flush calls <emit> for the incomplete line held by splitter, if any.
+func (l *lineSplitter) flush(emit func(line []byte) error) error {
+	if len(l.partial) == 0 {
+		return nil
+	}
+
+	err := emit(l.partial)
+	l.partial = l.partial[:0]
+	return err
+}
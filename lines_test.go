@@ -0,0 +1,30 @@
+package file_streamer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLineSplitter(t *testing.T) {
+	var lines []string
+	emit := func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	}
+
+	var splitter lineSplitter
+	for _, chunk := range []string{"one\ntw", "o\r\n", "", "\nthr", "ee"} {
+		if err := splitter.split([]byte(chunk), emit); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"one", "two", ""}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines %q, want %q", lines, want)
+	}
+
+	splitter.flush(emit)
+	if want := []string{"one", "two", "", "three"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines after flush %q, want %q", lines, want)
+	}
+}
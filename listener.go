@@ -2,8 +2,14 @@ package file_streamer
 
 import (
 	"bufio"
+	"errors"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+	"io"
 	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type (
@@ -16,21 +22,87 @@ type (
 // Use NewListener for getting initialized Listener structure ready for usage in Streamer.
 type Listener struct {
 	mu sync.Mutex
+	id ListenerID
 
-	file        *os.File      // read data from file
-	writeDataTo *bufio.Writer // file data will be written to this buffer
+	file        *os.File          // read data from file
+	watchPath   string            // normalized name of file, the key of subscriptions set by StreamTo()
+	writeDataTo *bufio.Writer     // file data will be written to this buffer
+	encoder     Encoder           // encodes file data before it is written to buffer, nil means 'write as is'
+	charset     encoding.Encoding // charset of file data to be converted to UTF-8, nil means 'no conversion'
+
+	transformers []transform.Transformer // applied to file data after charset conversion
+	skipHoles    bool                    // skip holes of sparse files
+	networkFS    bool                    // the file is on network file system, poll it instead of watching
+	flushPolicy  FlushPolicy
+
+	remoteAddr string // who receives file data, for audit events only
+	principal  string
+	labels     Labels
+	priority   Priority
+
+	checkpoints CheckpointStore // nil means 'no checkpoints'
+	consumer    string
 
 	newDataNotifications newDataChan
+	closed               chan struct{} // closed by Close(), newDataNotifications are never closed: Streamer writes there
 	isClosed             bool
+	closeErr             error // the reason Streamer closed the listener, returned by StreamTo()
+	isPaused             bool
+	streamState          uint8
+	stats                ListenerStats
+	summary              *StreamSummary // summary of the last finished stream
+	retryPolicy          RetryPolicy
+	seekRequest          *seekRequest // applied by Streamer before the next read
+
+	overflowPolicy       OverflowPolicy
+	overflowDeadline     time.Duration
+	droppedNotifications uint64 // updated atomically
+	memory               uint64 // size of stream buffers, updated atomically
+	fileCheckRequested   uint32 // 1 when file was removed or renamed, updated atomically
+
+	eventHandler EventHandler     // nil means 'no status events'
+	events       chan StreamEvent // created by Events()
+	eventsClosed bool             // the stream is finished, no more events are sent
+	heartbeat    time.Duration
+}
+
+var (
+	// ErrListenerInUse is returned by StreamTo() for a listener that is streaming data in another StreamTo() call.
+	ErrListenerInUse = errors.New("listener is already streaming")
+
+	// ErrListenerClosed is returned by StreamTo() for a closed listener that has already streamed data.
+	ErrListenerClosed = errors.New("listener is closed")
+)
+
+// Listener streaming states
+const (
+	listenerIdle      uint8 = iota // was never streamed
+	listenerStreaming              // StreamTo() is running
+	listenerStreamed               // StreamTo() has returned
+)
+
+// ErrInvalidSeek is returned by Listener.SeekTo() for unknown whence values and negative absolute offsets.
+var ErrInvalidSeek = errors.New("invalid seek position")
+
+// ListenerID identifies a Listener among all listeners of the process.
+type ListenerID uint64
+
+var lastListenerID uint64 // updated atomically
+
+type seekRequest struct {
+	offset int64
+	whence int
 }
 
 // NewListener creates initialized Listener ready to be provided to Streamer.StreamTo() function
 func NewListener(file *os.File, writeDataTo *bufio.Writer) *Listener {
 	l := &Listener{
+		id:          ListenerID(atomic.AddUint64(&lastListenerID, 1)),
 		file:        file,
 		writeDataTo: writeDataTo,
 
 		newDataNotifications: make(newDataChan, 100),
+		closed:               make(chan struct{}),
 		isClosed:             false,
 	}
 
@@ -40,9 +112,55 @@ func NewListener(file *os.File, writeDataTo *bufio.Writer) *Listener {
 	return l
 }
 
+// SetEncoder makes Streamer to encode file data with <encoder> before writing it to Listener's buffered writer.
+// Each encoded chunk is flushed separately (see Encoder).
+//
+// Should be called before passing Listener to Streamer.StreamTo(): Streamer reads the encoder once when stream starts.
+func (bs *Listener) SetEncoder(encoder Encoder) {
+	bs.mu.Lock()
+	bs.encoder = encoder
+	bs.mu.Unlock()
+}
+
+// SetCharset makes Streamer to convert file data from <charset> to UTF-8 before writing it to Listener's buffered
+// writer. Use encodings from golang.org/x/text/encoding subpackages, e.g. charmap.ISO8859_1 or japanese.ShiftJIS.
+//
+// Multi-byte sequences split between two file reads are converted correctly: the incomplete part of a sequence is
+// held until the rest of it is written to the file. When stream ends in the middle of a sequence, the incomplete part
+// is silently dropped.
+//
+// Should be called before passing Listener to Streamer.StreamTo(): Streamer reads the charset once when stream starts.
+func (bs *Listener) SetCharset(charset encoding.Encoding) {
+	bs.mu.Lock()
+	bs.charset = charset
+	bs.mu.Unlock()
+}
+
+// AddTransformer adds <t> to the list of transformations applied to file data before it is written to Listener's
+// buffered writer (after charset conversion, but before encoding). Transformers are applied in the order they were
+// added. See NewANSIToHTML() and NewANSIStripper() for built-in ones.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) AddTransformer(t transform.Transformer) {
+	bs.mu.Lock()
+	bs.transformers = append(bs.transformers, t)
+	bs.mu.Unlock()
+}
+
+// SetAuditInfo binds the stream consumer identity to Listener. Streamer passes it to AuditSink in each audit event.
+//
+// <remoteAddr> is the client network address, <principal> is the authenticated user name (or any other identity
+// provided by your authentication layer).
+func (bs *Listener) SetAuditInfo(remoteAddr, principal string) {
+	bs.mu.Lock()
+	bs.remoteAddr = remoteAddr
+	bs.principal = principal
+	bs.mu.Unlock()
+}
+
 // Close prevents Streamer to stream any more data to this listener.
 //
-// Listeners are not reusable. Reuse of closed listener will cause streamer to stop streaming immediately.
+// Listeners are not reusable. StreamTo() of closed listener that has already streamed data returns ErrListenerClosed.
 // There is one exception for it. The code:
 //
 //   l := NewListener(...)
@@ -51,6 +169,11 @@ func NewListener(file *os.File, writeDataTo *bufio.Writer) *Listener {
 //
 // will cause streamer to read data from file exactly once. It implements 'cat' utility -like behaviour
 func (bs *Listener) Close() {
+	bs.closeWithError(nil)
+}
+
+// closeWithError closes the listener making StreamTo() to return <err> instead of reading pending data.
+func (bs *Listener) closeWithError(err error) {
 	bs.mu.Lock()
 
 	if bs.isClosed {
@@ -58,12 +181,64 @@ func (bs *Listener) Close() {
 		return
 	}
 
-	close(bs.newDataNotifications)
+	close(bs.closed)
 	bs.isClosed = true
+	bs.closeErr = err
+
+	bs.mu.Unlock()
+}
+
+// startStreaming marks the listener as streaming. Listeners are single-use: returns ErrListenerInUse when another
+// stream uses the listener right now and ErrListenerClosed when the listener was streamed and closed.
+func (bs *Listener) startStreaming() error {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	switch {
+	case bs.streamState == listenerStreaming:
+		return ErrListenerInUse
+	case bs.streamState == listenerStreamed && bs.isClosed:
+		return ErrListenerClosed
+	}
 
+	bs.streamState = listenerStreaming
+	return nil
+}
+
+func (bs *Listener) finishStreaming() {
+	bs.mu.Lock()
+	bs.streamState = listenerStreamed
 	bs.mu.Unlock()
 }
 
+func (bs *Listener) closeError() error {
+	bs.mu.Lock()
+	err := bs.closeErr
+	bs.mu.Unlock()
+
+	return err
+}
+
+// File returns the file Listener streams data from.
+func (bs *Listener) File() *os.File {
+	return bs.file
+}
+
+// ID returns unique identifier of the listener, to refer it in Streamer.CloseStream() and StreamInfo.
+func (bs *Listener) ID() ListenerID {
+	return bs.id
+}
+
+// Writer returns the buffered writer Listener streams file data to.
+func (bs *Listener) Writer() *bufio.Writer {
+	return bs.writeDataTo
+}
+
+// Closed returns a channel that is closed when Listener is closed.
+func (bs *Listener) Closed() <-chan struct{} {
+	return bs.closed
+}
+
 // IsClosed returns true when listener is not available to receive data from the file and send it to the buffer any more.
 func (bs *Listener) IsClosed() bool {
 	bs.mu.Lock()
@@ -72,3 +247,90 @@ func (bs *Listener) IsClosed() bool {
 
 	return closed
 }
+
+// Pause temporarily freezes the stream: Streamer keeps the subscription and file position, but does not read new data
+// until Resume() is called. The data written to the file meanwhile is streamed after Resume().
+//
+// File changes during the pause still count as activity for StreamTo() timeout.
+func (bs *Listener) Pause() {
+	bs.mu.Lock()
+	bs.isPaused = true
+	bs.mu.Unlock()
+}
+
+// Resume continues the stream paused by Pause(), sending all data accumulated in file during the pause.
+func (bs *Listener) Resume() {
+	bs.mu.Lock()
+	bs.isPaused = false
+	bs.mu.Unlock()
+
+	// wake up the stream: no notification may come when the file was not changed during the pause
+	select {
+	case bs.newDataNotifications <- newDataEvent{}:
+	default:
+	}
+}
+
+// IsPaused reports whether the stream is paused with Pause().
+func (bs *Listener) IsPaused() bool {
+	bs.mu.Lock()
+	paused := bs.isPaused
+	bs.mu.Unlock()
+
+	return paused
+}
+
+// SeekTo re-positions the live stream, like os.File.Seek() does. The new position is applied by Streamer before the
+// next read, never in the middle of one, so the stream continues from <offset> right after the data being sent now.
+//
+// io.SeekEnd is relative to the size of file at the moment of the call. Returns ErrInvalidSeek for unknown <whence>
+// and negative absolute offsets. When the resulting position is invalid
+// for other reasons, the seek is ignored and the stream continues from the current position.
+func (bs *Listener) SeekTo(offset int64, whence int) error {
+	switch whence {
+	case io.SeekStart:
+		if offset < 0 {
+			return ErrInvalidSeek
+		}
+	case io.SeekEnd:
+		// the end of file at the moment of the call, not when Streamer applies it
+		info, err := bs.file.Stat()
+		if err != nil {
+			return err
+		}
+		offset, whence = info.Size()+offset, io.SeekStart
+	case io.SeekCurrent:
+	default:
+		return ErrInvalidSeek
+	}
+
+	bs.mu.Lock()
+	bs.seekRequest = &seekRequest{offset: offset, whence: whence}
+	bs.mu.Unlock()
+
+	// wake up the stream to apply the new position even when file does not change
+	select {
+	case bs.newDataNotifications <- newDataEvent{}:
+	default:
+	}
+
+	return nil
+}
+
+// SeekToEnd skips all data not streamed yet, continuing the stream from the current end of file.
+func (bs *Listener) SeekToEnd() error {
+	return bs.SeekTo(0, io.SeekEnd)
+}
+
+func (bs *Listener) takeSeekRequest() (seekRequest, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.seekRequest == nil {
+		return seekRequest{}, false
+	}
+
+	seek := *bs.seekRequest
+	bs.seekRequest = nil
+	return seek, true
+}
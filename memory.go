@@ -0,0 +1,46 @@
+package file_streamer
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrMemoryBudgetExceeded is returned by StreamTo() when stream buffers do not fit into Streamer's memory budget.
+var ErrMemoryBudgetExceeded = errors.New("streamer memory budget exceeded")
+
+// SetMemoryBudget limits the total size of read buffers of all streams to <bytes>, so a burst of clients on huge files
+// can't exhaust process memory. A stream that does not fit into the budget is rejected: its StreamTo() returns
+// ErrMemoryBudgetExceeded right away. Current usage is reported by Metrics() and ActiveStreams().
+//
+// Zero <bytes> (the default) means 'no limit'. Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetMemoryBudget(bytes uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.memoryBudget = bytes
+	return nil
+}
+
+// reserveMemory accounts <bytes> of a new stream buffers. Returns false when they do not fit into the budget.
+func (s *Streamer) reserveMemory(bytes uint64) bool {
+	for {
+		used := atomic.LoadUint64(&s.metrics.MemoryUsage)
+		if s.memoryBudget != 0 && used+bytes > s.memoryBudget {
+			atomic.AddUint64(&s.metrics.RejectedStreams, 1)
+			return false
+		}
+
+		if atomic.CompareAndSwapUint64(&s.metrics.MemoryUsage, used, used+bytes) {
+			return true
+		}
+	}
+}
+
+// releaseMemory returns <bytes> reserved by reserveMemory() to the budget.
+func (s *Streamer) releaseMemory(bytes uint64) {
+	atomic.AddUint64(&s.metrics.MemoryUsage, ^(bytes - 1))
+}
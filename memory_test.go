@@ -0,0 +1,54 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"testing"
+)
+
+func TestMemoryBudget(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	if err := s.SetMemoryBudget(6000); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	if err := s.SetMemoryBudget(0); err != ErrRunning {
+		t.Errorf("budget change of running streamer returned %v, want %v", err, ErrRunning)
+	}
+
+	name := createTestFile(t, "data")
+	first, firstResult := startTestStream(t, s, name) // 4096 bytes buffer
+
+	if info := s.ActiveStreams(); len(info) != 1 || info[0].Memory != 4096 {
+		t.Errorf("unexpected active streams %+v", info)
+	}
+
+	rejected := NewListener(openTestFile(t, name), bufio.NewWriter(ioutil.Discard))
+	if err := s.StreamTo(rejected, 0); err != ErrMemoryBudgetExceeded {
+		t.Fatalf("stream over budget returned %v, want %v", err, ErrMemoryBudgetExceeded)
+	}
+
+	// smaller buffer still fits
+	small := NewListener(openTestFile(t, name), bufio.NewWriterSize(ioutil.Discard, 1024))
+	small.Close()
+	if err := s.StreamTo(small, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if metrics := s.Metrics(); metrics.MemoryUsage != 4096 || metrics.RejectedStreams != 1 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+
+	first.Close()
+	if err := <-firstResult; err != nil {
+		t.Fatal(err)
+	}
+	if usage := s.Metrics().MemoryUsage; usage != 0 {
+		t.Errorf("%d bytes are still accounted after all streams finished", usage)
+	}
+}
@@ -0,0 +1,98 @@
+package file_streamer
+
+import "sync"
+
+// MessageSink publishes messages to a pub/sub broker. Implement it to add a new broker: MessageWriter does all the
+// rest.
+//
+// Publish() must not retain <data> after it returns: the writer reuses its buffers.
+type MessageSink interface {
+	Publish(topic string, data []byte) error
+}
+
+// MessageSinkFunc is an adapter to allow the use of ordinary functions as message sinks.
+type MessageSinkFunc func(topic string, data []byte) error
+
+// Publish calls f(topic, data).
+func (f MessageSinkFunc) Publish(topic string, data []byte) error {
+	return f(topic, data)
+}
+
+// NATSPublisher is the part of *nats.Conn client used by NATS sink.
+type NATSPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// NewNATSSink creates a MessageSink that publishes messages to NATS subjects. Pass *nats.Conn (or *nats.EncodedConn
+// wrapper with the same method) as <conn>.
+func NewNATSSink(conn NATSPublisher) MessageSink {
+	return MessageSinkFunc(conn.Publish)
+}
+
+// MQTTPublisher publishes a message and waits for its delivery according to QoS level. Paho client returns a token
+// instead, so wrap it:
+//
+//	func(topic string, qos byte, retained bool, payload []byte) error {
+//		token := client.Publish(topic, qos, retained, payload)
+//		token.Wait()
+//		return token.Error()
+//	}
+type MQTTPublisher func(topic string, qos byte, retained bool, payload []byte) error
+
+// NewMQTTSink creates a MessageSink that publishes messages to MQTT topics with <qos> level and <retained> flag.
+func NewMQTTSink(publish MQTTPublisher, qos byte, retained bool) MessageSink {
+	return MessageSinkFunc(func(topic string, data []byte) error {
+		return publish(topic, qos, retained, data)
+	})
+}
+
+// MessageWriterOptions configures MessageWriter.
+type MessageWriterOptions struct {
+	PerLine bool // publish each line as a separate message instead of each flushed chunk
+}
+
+// MessageWriter publishes data written to it to a MessageSink topic, fanning live file data out to pub/sub
+// consumers. Each Write() call is a single message, or each line is in PerLine mode (see KafkaWriter).
+type MessageWriter struct {
+	sink    MessageSink
+	topic   string
+	perLine bool
+
+	mu    sync.Mutex
+	lines lineSplitter
+}
+
+// NewMessageWriter creates a writer that publishes data to <topic> (NATS subject, MQTT topic and so on) of <sink>.
+func NewMessageWriter(sink MessageSink, topic string, options MessageWriterOptions) *MessageWriter {
+	return &MessageWriter{sink: sink, topic: topic, perLine: options.PerLine}
+}
+
+func (m *MessageWriter) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var err error
+	if m.perLine {
+		err = m.lines.split(p, m.publish)
+	} else {
+		err = m.publish(p)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush publishes the incomplete line held in PerLine mode.
+func (m *MessageWriter) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.lines.flush(m.publish)
+}
+
+func (m *MessageWriter) publish(data []byte) error {
+	return m.sink.Publish(m.topic, data)
+}
@@ -0,0 +1,53 @@
+package file_streamer
+
+import (
+	"bufio"
+	"testing"
+)
+
+type natsRecorder struct {
+	subjects []string
+	messages []string
+}
+
+func (r *natsRecorder) Publish(subject string, data []byte) error {
+	r.subjects = append(r.subjects, subject)
+	r.messages = append(r.messages, string(data))
+	return nil
+}
+
+func TestMessageWriterNATS(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "one\ntwo\n")
+
+	conn := &natsRecorder{}
+	writer := NewMessageWriter(NewNATSSink(conn), "logs.app", MessageWriterOptions{PerLine: true})
+	catFile(t, s, NewListener(openTestFile(t, name), bufio.NewWriter(writer)))
+
+	if len(conn.messages) != 2 || conn.messages[0] != "one" || conn.messages[1] != "two" {
+		t.Errorf("published %q, want one message per line", conn.messages)
+	}
+	for _, subject := range conn.subjects {
+		if subject != "logs.app" {
+			t.Errorf("published to %q, want logs.app", subject)
+		}
+	}
+}
+
+func TestMessageWriterMQTT(t *testing.T) {
+	var published []string
+	sink := NewMQTTSink(func(topic string, qos byte, retained bool, payload []byte) error {
+		if qos != 1 || !retained {
+			t.Errorf("published with qos %d and retained %v, want 1 and true", qos, retained)
+		}
+		published = append(published, topic+":"+string(payload))
+		return nil
+	}, 1, true)
+
+	writer := NewMessageWriter(sink, "logs/app", MessageWriterOptions{})
+	writer.Write([]byte("chunk\n"))
+
+	if len(published) != 1 || published[0] != "logs/app:chunk\n" {
+		t.Errorf("published %q, want the whole chunk", published)
+	}
+}
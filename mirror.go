@@ -0,0 +1,169 @@
+package file_streamer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// MirrorOptions configures Streamer.MirrorFile().
+type MirrorOptions struct {
+	// Append opens destination file with O_APPEND, so other writers (or restarted mirrors) never overwrite its data.
+	Append bool
+
+	// Resume starts copying from the offset equal to the current size of destination file, continuing the mirror
+	// after restart or crash. Without Resume destination file is truncated and the source is copied from the beginning.
+	Resume bool
+
+	// SyncInterval controls fsync(2) of destination file: 0 syncs after each portion of data, positive interval syncs
+	// not more often than once per interval (and once more when mirroring stops), negative one never syncs leaving it
+	// to the OS.
+	SyncInterval time.Duration
+
+	// Timeout stops mirroring after the source file was not changed for this long, 0 means 'until <ctx> is done or the
+	// source file is removed'.
+	Timeout time.Duration
+
+	Perm os.FileMode // permissions of created destination file, 0644 by default
+}
+
+// MirrorFile continuously replicates append-only file <src> into <dst> (on another disk or network mount) until <ctx>
+// is done, the source file is removed or options.Timeout expires.
+//
+// Listener's buffered writer is flushed after each portion of data, so at most one portion is lost on crash. Use
+// options.Resume to continue mirroring from the destination size after restart.
+func (s *Streamer) MirrorFile(ctx context.Context, src, dst string, options MirrorOptions) error {
+	if !s.IsRunning() {
+		return ErrNotRunning
+	}
+
+	if options.Perm == 0 {
+		options.Perm = 0644
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if options.Append {
+		flags |= os.O_APPEND
+	}
+	if !options.Resume {
+		flags |= os.O_TRUNC
+	}
+
+	dstFile, err := os.OpenFile(dst, flags, options.Perm)
+	if err != nil {
+		return err
+	}
+	defer dstFile.Close()
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	if options.Resume {
+		offset, err := dstFile.Seek(0, io.SeekEnd)
+		if err != nil {
+			return err
+		}
+
+		if _, err = srcFile.Seek(offset, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	mirror := &mirrorWriter{
+		file:         dstFile,
+		clock:        s.clock,
+		syncInterval: options.SyncInterval,
+		lastSync:     s.clock.Now(),
+	}
+
+	if options.SyncInterval > 0 {
+		done := make(chan empty)
+		defer close(done)
+		go mirror.syncOnTimer(done)
+	}
+
+	err = s.StreamToContext(ctx, NewListener(srcFile, bufio.NewWriter(mirror)), options.Timeout)
+	if ctx.Err() != nil && err == ctx.Err() {
+		err = nil // cancellation is the regular way to stop mirroring
+	}
+
+	if options.SyncInterval >= 0 {
+		if syncErr := mirror.sync(); err == nil {
+			err = syncErr
+		}
+	}
+
+	return err
+}
+
+// mirrorWriter writes data to destination file and syncs it according to sync interval.
+type mirrorWriter struct {
+	file         *os.File
+	clock        Clock
+	syncInterval time.Duration
+
+	mu       sync.Mutex
+	dirty    bool // there is data written after the last sync
+	lastSync time.Time
+}
+
+func (m *mirrorWriter) Write(p []byte) (int, error) {
+	n, err := m.file.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.dirty = true
+	if m.syncInterval == 0 || m.syncInterval > 0 && m.clock.Now().Sub(m.lastSync) >= m.syncInterval {
+		return n, m.syncLocked()
+	}
+
+	return n, nil
+}
+
+// syncOnTimer syncs data written after the last sync once per sync interval, so the file is synced even when the
+// source file stops changing.
+func (m *mirrorWriter) syncOnTimer(done chan empty) {
+	timer := m.clock.NewTimer(m.syncInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C():
+			_ = m.sync()
+			timer.Reset(m.syncInterval)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (m *mirrorWriter) sync() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.syncLocked()
+}
+
+func (m *mirrorWriter) syncLocked() error {
+	if !m.dirty {
+		return nil
+	}
+
+	if err := m.file.Sync(); err != nil {
+		return err
+	}
+
+	m.dirty = false
+	m.lastSync = m.clock.Now()
+	return nil
+}
@@ -0,0 +1,99 @@
+package file_streamer
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func mirrorTestDst(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "file-streamer-mirror")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return filepath.Join(dir, "mirror")
+}
+
+func readTestFile(t *testing.T, name string) string {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestMirrorFile(t *testing.T) {
+	s := startTestStreamer(t)
+	src := createTestFile(t, "first\n")
+	dst := mirrorTestDst(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- s.MirrorFile(ctx, src, dst, MirrorOptions{Append: true, SyncInterval: time.Millisecond})
+	}()
+
+	waitForContents(t, dst, "first\n")
+	appendToFile(t, src, "second\n")
+	waitForContents(t, dst, "first\nsecond\n")
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMirrorFileResume(t *testing.T) {
+	s := startTestStreamer(t)
+	src := createTestFile(t, "mirrored\nnew\n")
+	dst := mirrorTestDst(t)
+
+	if err := ioutil.WriteFile(dst, []byte("mirrored\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.MirrorFile(context.Background(), src, dst, MirrorOptions{Resume: true, Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data := readTestFile(t, dst); data != "mirrored\nnew\n" {
+		t.Errorf("mirror contains %q after resume", data)
+	}
+}
+
+func TestMirrorFileTruncatesWithoutResume(t *testing.T) {
+	s := startTestStreamer(t)
+	src := createTestFile(t, "source")
+	dst := mirrorTestDst(t)
+
+	if err := ioutil.WriteFile(dst, []byte("stale data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := s.MirrorFile(context.Background(), src, dst, MirrorOptions{SyncInterval: -1, Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data := readTestFile(t, dst); data != "source" {
+		t.Errorf("mirror contains %q, want %q", data, "source")
+	}
+}
+
+func waitForContents(t *testing.T, name, want string) {
+	for i := 0; i < 2000; i++ {
+		if data, _ := ioutil.ReadFile(name); string(data) == want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("%s does not contain %q", name, want)
+}
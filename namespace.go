@@ -0,0 +1,268 @@
+package file_streamer
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	// ErrInvalidNamespace is returned by Streamer.RegisterNamespace() for namespaces without name or root.
+	ErrInvalidNamespace = errors.New("namespace must have a name and a root")
+
+	// ErrNamespaceExists is returned by Streamer.RegisterNamespace() when the name is taken already.
+	ErrNamespaceExists = errors.New("namespace is already registered")
+
+	// ErrUnknownNamespace is returned for names of namespaces that were not registered.
+	ErrUnknownNamespace = errors.New("unknown namespace")
+
+	// ErrPathOutsideNamespace is returned by Streamer.ResolveInNamespace() for paths that lead out of namespace root,
+	// through symlinks for example.
+	ErrPathOutsideNamespace = errors.New("path is outside of namespace root")
+
+	// ErrNamespaceLimit is returned by Streamer.StreamTo() when namespace of the file has Namespace.MaxStreams streams
+	// already.
+	ErrNamespaceLimit = errors.New("namespace streams limit is reached")
+)
+
+// Namespace is a named root directory of files served by Streamer, so one Streamer can safely serve logs of multiple
+// teams: each namespace has its own streams limit, authorization hook and metrics.
+type Namespace struct {
+	Name string
+	Root string
+
+	MaxStreams int // the limit of concurrent streams of namespace files, 0 means 'no limit'
+
+	// Authorize is called by Streamer.ResolveInNamespace() with <principal> (user name or any other identity) and
+	// the requested path relative to Root. Non-nil error denies the access. Nil Authorize allows everything.
+	Authorize func(principal, path string) error
+
+	// Labels are added to labels of all streams of namespace files (see Listener.SetLabels()) along with
+	// 'namespace' label holding Name.
+	Labels Labels
+}
+
+// NamespaceMetrics are stream counters of a namespace.
+type NamespaceMetrics struct {
+	ActiveStreams   uint64
+	TotalStreams    uint64
+	RejectedStreams uint64 // streams rejected because of Namespace.MaxStreams limit
+	BytesStreamed   uint64
+}
+
+type namespace struct {
+	Namespace
+	root string // canonical path of Root
+
+	metrics NamespaceMetrics // updated atomically
+}
+
+// contains tells whether canonical <filePath> is inside of namespace root.
+func (ns *namespace) contains(filePath string) bool {
+	return isInside(ns.root, filePath)
+}
+
+func (ns *namespace) acquire() bool {
+	for {
+		active := atomic.LoadUint64(&ns.metrics.ActiveStreams)
+		if ns.MaxStreams > 0 && active >= uint64(ns.MaxStreams) {
+			atomic.AddUint64(&ns.metrics.RejectedStreams, 1)
+			return false
+		}
+
+		if atomic.CompareAndSwapUint64(&ns.metrics.ActiveStreams, active, active+1) {
+			atomic.AddUint64(&ns.metrics.TotalStreams, 1)
+			return true
+		}
+	}
+}
+
+func (ns *namespace) release(streamed uint64) {
+	atomic.AddUint64(&ns.metrics.ActiveStreams, ^uint64(0))
+	atomic.AddUint64(&ns.metrics.BytesStreamed, streamed)
+}
+
+// RegisterNamespace adds <ns> to namespaces of Streamer. Streams of all files inside of namespace root are counted
+// in namespace metrics and limited by its MaxStreams, whatever way they were started. When roots of namespaces are
+// nested, files belong to the innermost one.
+func (s *Streamer) RegisterNamespace(ns Namespace) error {
+	if ns.Name == "" || ns.Root == "" {
+		return ErrInvalidNamespace
+	}
+
+	registered := &namespace{Namespace: ns, root: canonicalPath(ns.Root)}
+	registered.Labels = ns.Labels.clone()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.namespaces[ns.Name]; exists {
+		return ErrNamespaceExists
+	}
+
+	s.namespaces[ns.Name] = registered
+	return nil
+}
+
+// ResolveInNamespace returns path of the file <filePath> (slash-separated, relative to the root of namespace <name>)
+// after checking <principal> has access to it with Namespace.Authorize. Paths can't escape the root: neither with '..'
+// elements, nor through symlinks. Returns the error of Namespace.Authorize when access is denied.
+func (s *Streamer) ResolveInNamespace(name, filePath, principal string) (string, error) {
+	s.mu.Lock()
+	ns, exists := s.namespaces[name]
+	s.mu.Unlock()
+
+	if !exists {
+		return "", ErrUnknownNamespace
+	}
+
+	// cleaning of rooted path removes all '..' elements
+	relative := strings.TrimPrefix(path.Clean("/"+filePath), "/")
+	resolved := filepath.Join(ns.Root, filepath.FromSlash(relative))
+
+	if _, err := os.Lstat(resolved); err == nil && !ns.contains(canonicalPath(resolved)) {
+		return "", ErrPathOutsideNamespace
+	}
+
+	if ns.Authorize != nil {
+		if err := ns.Authorize(principal, relative); err != nil {
+			return "", err
+		}
+	}
+
+	return resolved, nil
+}
+
+// NamespaceMetrics returns metrics of namespace <name>, false when there is no such namespace.
+func (s *Streamer) NamespaceMetrics(name string) (NamespaceMetrics, bool) {
+	s.mu.Lock()
+	ns, exists := s.namespaces[name]
+	s.mu.Unlock()
+
+	if !exists {
+		return NamespaceMetrics{}, false
+	}
+
+	return NamespaceMetrics{
+		ActiveStreams:   atomic.LoadUint64(&ns.metrics.ActiveStreams),
+		TotalStreams:    atomic.LoadUint64(&ns.metrics.TotalStreams),
+		RejectedStreams: atomic.LoadUint64(&ns.metrics.RejectedStreams),
+		BytesStreamed:   atomic.LoadUint64(&ns.metrics.BytesStreamed),
+	}, true
+}
+
+// namespaceOf returns the innermost namespace the file <name> belongs to, nil when there is none.
+func (s *Streamer) namespaceOf(name string) *namespace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.namespaces) == 0 {
+		return nil
+	}
+
+	filePath := canonicalPath(name)
+
+	var found *namespace
+	for _, ns := range s.namespaces {
+		if ns.contains(filePath) && (found == nil || len(ns.root) > len(found.root)) {
+			found = ns
+		}
+	}
+
+	return found
+}
+
+// addNamespaceLabels adds labels of <ns> to labels of Listener, the labels set by Listener.SetLabels() win.
+func (bs *Listener) addNamespaceLabels(ns *namespace) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	labels := make(Labels, len(ns.Labels)+len(bs.labels)+1)
+	for key, value := range ns.Labels {
+		labels[key] = value
+	}
+	for key, value := range bs.labels {
+		labels[key] = value
+	}
+	labels["namespace"] = ns.Name
+
+	bs.labels = labels
+}
+
+// NamespaceHandler returns http.Handler that streams files of Streamer's namespaces: the first element of request
+// path is the namespace name, the rest is the path of the file relative to namespace root. Access is checked with
+// Namespace.Authorize for the principal returned by <principal> (nil means 'anonymous'), query parameters and the
+// rest of options are the same as for Handler(). Each file of archive downloads is checked separately, after glob
+// patterns are expanded.
+func NamespaceHandler(streamer *Streamer, options HandlerOptions, principal func(req *http.Request) string) http.Handler {
+	return &namespaceHandler{streamer: streamer, options: options, principal: principal, lineCounts: NewLineCountCache()}
+}
+
+type namespaceHandler struct {
+	streamer   *Streamer
+	options    HandlerOptions
+	principal  func(req *http.Request) string
+	lineCounts *LineCountCache
+}
+
+func (h *namespaceHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	requestPath := strings.TrimPrefix(path.Clean("/"+req.URL.Path), "/")
+
+	name, filePath := requestPath, ""
+	if i := strings.IndexByte(requestPath, '/'); i >= 0 {
+		name, filePath = requestPath[:i], requestPath[i+1:]
+	}
+
+	var principal string
+	if h.principal != nil {
+		principal = h.principal(req)
+	}
+
+	h.streamer.mu.Lock()
+	ns, exists := h.streamer.namespaces[name]
+	h.streamer.mu.Unlock()
+
+	if !exists {
+		http.Error(w, ErrUnknownNamespace.Error(), http.StatusNotFound)
+		return
+	}
+
+	// archive paths may be glob patterns: matched files are checked one by one instead
+	isArchive := h.options.Mode == ModeZip || h.options.Mode == ModeTarGz || req.URL.Query().Get("download") != ""
+	if !isArchive {
+		if _, err := h.streamer.ResolveInNamespace(name, filePath, principal); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+	}
+
+	authorize := func(archived string) error {
+		relative, err := filepath.Rel(ns.Root, archived)
+		if err != nil {
+			return ErrPathOutsideNamespace
+		}
+
+		_, err = h.streamer.ResolveInNamespace(name, filepath.ToSlash(relative), principal)
+		return err
+	}
+
+	// streamHandler resolves the path the same way ResolveInNamespace() did
+	nsReq := new(http.Request)
+	*nsReq = *req
+	nsURL := *req.URL
+	nsURL.Path = filePath
+	nsReq.URL = &nsURL
+
+	handler := &streamHandler{
+		streamer:   h.streamer,
+		root:       ns.Root,
+		options:    h.options,
+		lineCounts: h.lineCounts,
+		authorize:  authorize,
+	}
+	handler.ServeHTTP(w, nsReq)
+}
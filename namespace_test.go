@@ -0,0 +1,214 @@
+package file_streamer
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNamespaces(t *testing.T) {
+	s := startTestStreamer(t)
+
+	root, err := ioutil.TempDir("", "namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	errDenied := errors.New("denied")
+	err = s.RegisterNamespace(Namespace{
+		Name:       "app1",
+		Root:       root,
+		MaxStreams: 1,
+		Labels:     Labels{"team": "core"},
+		Authorize: func(principal, path string) error {
+			if principal != "alice" {
+				return errDenied
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.RegisterNamespace(Namespace{Name: "app1", Root: root}); err != ErrNamespaceExists {
+		t.Errorf("second registration returned %v, want %v", err, ErrNamespaceExists)
+	}
+
+	writeRootFile(t, root, "app.log", "data")
+
+	name, err := s.ResolveInNamespace("app1", "../../app.log", "alice")
+	if err != nil || name != filepath.Join(root, "app.log") {
+		t.Errorf("resolved to %q (%v)", name, err)
+	}
+	if _, err = s.ResolveInNamespace("app1", "app.log", "bob"); err != errDenied {
+		t.Errorf("unauthorized resolve returned %v, want %v", err, errDenied)
+	}
+	if _, err = s.ResolveInNamespace("app2", "app.log", "alice"); err != ErrUnknownNamespace {
+		t.Errorf("resolve in unknown namespace returned %v, want %v", err, ErrUnknownNamespace)
+	}
+
+	// symlinks can't lead out of the root
+	if err = os.Symlink("/etc/passwd", filepath.Join(root, "passwd")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.ResolveInNamespace("app1", "passwd", "alice"); err != ErrPathOutsideNamespace {
+		t.Errorf("resolve of symlink out of root returned %v, want %v", err, ErrPathOutsideNamespace)
+	}
+
+	first, result := startTestStream(t, s, name)
+
+	if info := s.ActiveStreams(); len(info) != 1 || info[0].Labels["namespace"] != "app1" || info[0].Labels["team"] != "core" {
+		t.Errorf("unexpected active streams %+v", info)
+	}
+
+	second := NewListener(openTestFile(t, name), bufio.NewWriter(ioutil.Discard))
+	if err = s.StreamTo(second, 0); err != ErrNamespaceLimit {
+		t.Errorf("stream over namespace limit returned %v, want %v", err, ErrNamespaceLimit)
+	}
+
+	// files out of namespaces are not limited
+	catFile(t, s, NewListener(openTestFile(t, createTestFile(t, "other")), bufio.NewWriter(ioutil.Discard)))
+
+	first.Close()
+	if err = <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	metrics, _ := s.NamespaceMetrics("app1")
+	if metrics != (NamespaceMetrics{TotalStreams: 1, RejectedStreams: 1, BytesStreamed: 4}) {
+		t.Errorf("unexpected namespace metrics %+v", metrics)
+	}
+}
+
+func TestNamespaceHandler(t *testing.T) {
+	s := startTestStreamer(t)
+
+	root, err := ioutil.TempDir("", "namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	s.RegisterNamespace(Namespace{
+		Name: "app1",
+		Root: root,
+		Authorize: func(principal, path string) error {
+			if principal != "alice" {
+				return errors.New("denied")
+			}
+			return nil
+		},
+	})
+	writeRootFile(t, root, "app.log", "one\ntwo\n")
+
+	principal := func(req *http.Request) string { return req.Header.Get("X-User") }
+	server := httptest.NewServer(NamespaceHandler(s, HandlerOptions{}, principal))
+	defer server.Close()
+
+	for path, status := range map[string]int{
+		"/app1/app.log?info": http.StatusOK,
+		"/app2/app.log?info": http.StatusNotFound,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+		req.Header.Set("X-User", "alice")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != status {
+			t.Errorf("%s: got status %d, want %d", path, resp.StatusCode, status)
+		}
+	}
+
+	resp, err := http.Get(server.URL + "/app1/app.log?info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("anonymous request got status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+// Glob patterns of archive downloads must not get around symlink checks and Authorize.
+func TestNamespaceArchiveSandbox(t *testing.T) {
+	s := startTestStreamer(t)
+
+	root, err := ioutil.TempDir("", "namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	var mu sync.Mutex
+	var authorized []string
+	s.RegisterNamespace(Namespace{
+		Name: "app1",
+		Root: root,
+		Authorize: func(principal, path string) error {
+			mu.Lock()
+			authorized = append(authorized, path)
+			mu.Unlock()
+
+			if path == "private.log" {
+				return errors.New("denied")
+			}
+			return nil
+		},
+	})
+	writeRootFile(t, root, "app.log", "app data")
+	if err = os.Symlink(createTestFile(t, "SECRET"), filepath.Join(root, "evil")); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(NamespaceHandler(s, HandlerOptions{}, nil))
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for path, want := range map[string]int{
+		"/app1/evil?download=zip": http.StatusForbidden,
+		"/app1/e*?download=zip":   http.StatusNotFound,
+		"/app1/app*?download=zip": http.StatusOK,
+	} {
+		status, body := get(path)
+		if status != want {
+			t.Errorf("%s: got status %d, want %d", path, status, want)
+		}
+		if strings.Contains(body, "SECRET") {
+			t.Errorf("%s: archive has data of the file outside of namespace", path)
+		}
+	}
+
+	writeRootFile(t, root, "private.log", "private data")
+	if status, _ := get("/app1/*.log?download=zip"); status != http.StatusForbidden {
+		t.Errorf("archive with denied file got status %d, want %d", status, http.StatusForbidden)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, path := range authorized {
+		if strings.ContainsAny(path, "*?[") {
+			t.Errorf("Authorize was called with pattern %q instead of file paths", path)
+		}
+	}
+}
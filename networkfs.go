@@ -0,0 +1,63 @@
+package file_streamer
+
+import "time"
+
+// Network file systems are polled this often by default, see Streamer.SetNetworkFSPollInterval().
+const defaultNetworkFSPollInterval = time.Second
+
+// SetNetworkFS declares that the file of Listener is on a network file system (NFS, CIFS and so on), where inotify
+// does not see changes made on other hosts. Streamer watches such files by polling their size and modification time
+// instead (see Streamer.SetNetworkFSPollInterval()).
+//
+// Calling it is needed only for file systems Streamer can't detect itself: NFS, CIFS/SMB, 9P and CephFS are detected
+// automatically on Linux.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetNetworkFS(enabled bool) {
+	bs.mu.Lock()
+	bs.networkFS = enabled
+	bs.mu.Unlock()
+}
+
+// SetNetworkFSPollInterval changes how often Streamer polls files on network file systems, 1 second by default.
+// Zero <interval> disables polling: such files are watched by the regular watcher like all the others and streams
+// may hang until the next change made on the host Streamer runs on.
+//
+// SetWatcherFactory() disables polling, so custom watchers get all files as is. Call SetNetworkFSPollInterval()
+// after it to poll network file systems next to a custom watcher.
+//
+// Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetNetworkFSPollInterval(interval time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.networkFSPollInterval = interval
+	return nil
+}
+
+// isNetworkListener tells whether the file of <listener> must be polled instead of watching it with the regular watcher.
+func (s *Streamer) isNetworkListener(listener *Listener) bool {
+	if s.networkFSPollInterval <= 0 {
+		return false
+	}
+
+	listener.mu.Lock()
+	declared := listener.networkFS
+	listener.mu.Unlock()
+
+	return declared || isNetworkFS(listener.watchPath)
+}
+
+// isNetworkPath is the pollOnly function of Streamer's watcher: subscribeListener() marks network paths before
+// watching them.
+//
+// Reading networkPaths without a lock is safe: standbyWatcher calls pollOnly only from its Add(), and Streamer calls
+// Add() only from subscribeListener(), which runs in eventsRouter goroutine, the owner of networkPaths.
+func (s *Streamer) isNetworkPath(name string) bool {
+	_, isNetwork := s.networkPaths[name]
+	return isNetwork
+}
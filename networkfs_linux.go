@@ -0,0 +1,24 @@
+package file_streamer
+
+import "syscall"
+
+// statfs(2) magic numbers of network file systems, not defined by syscall package.
+var networkFSTypes = map[uint32]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x01021997: "9p",
+	0x00c36400: "ceph",
+}
+
+// isNetworkFS tells whether <path> is on a network file system. Returns false when it can't be found out.
+func isNetworkFS(path string) bool {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return false
+	}
+
+	_, isNetwork := networkFSTypes[uint32(stat.Type)]
+	return isNetwork
+}
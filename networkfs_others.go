@@ -0,0 +1,9 @@
+//go:build !linux
+// +build !linux
+
+package file_streamer
+
+// isNetworkFS can't detect network file systems on platforms other than Linux: use Listener.SetNetworkFS() there.
+func isNetworkFS(path string) bool {
+	return false
+}
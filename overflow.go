@@ -0,0 +1,142 @@
+package file_streamer
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy defines what Streamer does when a file changes while Listener's notifications queue is full, which
+// happens when listener's writer is slower than the file writer.
+type OverflowPolicy uint8
+
+const (
+	// OverflowCoalesce drops the notification: the listener reads all new data on one of the queued notifications
+	// anyway. This is the default policy.
+	OverflowCoalesce OverflowPolicy = iota
+
+	// OverflowBlock makes Streamer to wait for free space in the queue up to configured deadline, dropping the
+	// notification after that. Keep in mind Streamer does not notify other listeners while it waits.
+	OverflowBlock
+
+	// OverflowTerminate closes the listener: its StreamTo() returns ErrNotificationsOverflow.
+	OverflowTerminate
+)
+
+// ErrNotificationsOverflow is returned by StreamTo() when listener was terminated by OverflowTerminate policy.
+var ErrNotificationsOverflow = errors.New("listener notifications queue overflow")
+
+// StreamInfo describes an active stream.
+type StreamInfo struct {
+	ID                   ListenerID
+	File                 string
+	DroppedNotifications uint64   // notifications dropped because of listener's queue overflow
+	Memory               uint64   // size of stream buffers, accounted in Streamer's memory budget
+	Labels               Labels   // see Listener.SetLabels()
+	Priority             Priority // see Listener.SetPriority()
+}
+
+// Metrics are Streamer-wide counters.
+type Metrics struct {
+	DroppedNotifications uint64 // total number of notifications dropped because of listeners' queues overflow
+	TerminatedListeners  uint64 // number of listeners closed by OverflowTerminate policy
+	MemoryUsage          uint64 // total size of buffers of active streams (see SetMemoryBudget)
+	RejectedStreams      uint64 // number of streams rejected because of memory budget
+	FileChecks           uint64 // number of streamed files state checks (stat syscalls)
+	EventQueuePeak       uint64 // the longest file events queue seen, see SetEventQueue()
+	EventQueueAlerts     uint64 // times the file events queue reached its high-water mark
+	EventQueueOverflows  uint64 // file events that found the queue full
+	DroppedEvents        uint64 // file events dropped because of the queue policy
+}
+
+// Metrics returns current values of Streamer counters.
+func (s *Streamer) Metrics() Metrics {
+	return Metrics{
+		DroppedNotifications: atomic.LoadUint64(&s.metrics.DroppedNotifications),
+		TerminatedListeners:  atomic.LoadUint64(&s.metrics.TerminatedListeners),
+		MemoryUsage:          atomic.LoadUint64(&s.metrics.MemoryUsage),
+		RejectedStreams:      atomic.LoadUint64(&s.metrics.RejectedStreams),
+		FileChecks:           atomic.LoadUint64(&s.metrics.FileChecks),
+		EventQueuePeak:       atomic.LoadUint64(&s.metrics.EventQueuePeak),
+		EventQueueAlerts:     atomic.LoadUint64(&s.metrics.EventQueueAlerts),
+		EventQueueOverflows:  atomic.LoadUint64(&s.metrics.EventQueueOverflows),
+		DroppedEvents:        atomic.LoadUint64(&s.metrics.DroppedEvents),
+	}
+}
+
+// ActiveStreams returns information about all streams subscribed for file changes. Returns nil when Streamer is not
+// running.
+func (s *Streamer) ActiveStreams() []StreamInfo {
+	var info []StreamInfo
+	s.callRouter(func() {
+		for _, listeners := range s.subscriptions {
+			for _, listener := range listeners {
+				info = append(info, listener.info())
+			}
+		}
+	})
+
+	return info
+}
+
+// notifyListener sends 'new data' notification to <listener> according to its overflow policy.
+func (s *Streamer) notifyListener(listener *Listener) {
+	select {
+	case listener.newDataNotifications <- newDataEvent{}:
+		return
+	default:
+	}
+
+	listener.mu.Lock()
+	policy, deadline := listener.overflowPolicy, listener.overflowDeadline
+	listener.mu.Unlock()
+
+	switch policy {
+	case OverflowBlock:
+		timer := s.clock.NewTimer(deadline)
+		defer timer.Stop()
+
+		select {
+		case listener.newDataNotifications <- newDataEvent{}:
+			return
+		case <-listener.closed:
+			return
+		case <-timer.C():
+		}
+
+	case OverflowTerminate:
+		s.logger.Printf("File '%s' listener notifications queue overflow, terminating the listener", listener.file.Name())
+		atomic.AddUint64(&s.metrics.TerminatedListeners, 1)
+		listener.closeWithError(ErrNotificationsOverflow)
+	}
+
+	atomic.AddUint64(&listener.droppedNotifications, 1)
+	atomic.AddUint64(&s.metrics.DroppedNotifications, 1)
+}
+
+// SetOverflowPolicy defines what Streamer does when file changes while listener's notifications queue is full.
+// <deadline> is used by OverflowBlock policy only.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetOverflowPolicy(policy OverflowPolicy, deadline time.Duration) {
+	bs.mu.Lock()
+	bs.overflowPolicy = policy
+	bs.overflowDeadline = deadline
+	bs.mu.Unlock()
+}
+
+// DroppedNotifications returns the number of notifications dropped because of listener's queue overflow.
+func (bs *Listener) DroppedNotifications() uint64 {
+	return atomic.LoadUint64(&bs.droppedNotifications)
+}
+
+func (bs *Listener) info() StreamInfo {
+	return StreamInfo{
+		ID:                   bs.id,
+		File:                 bs.file.Name(),
+		DroppedNotifications: bs.DroppedNotifications(),
+		Memory:               atomic.LoadUint64(&bs.memory),
+		Labels:               bs.Labels(),
+		Priority:             bs.Priority(),
+	}
+}
@@ -0,0 +1,111 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"testing"
+	"time"
+)
+
+func newOverflowingListener(t *testing.T, policy OverflowPolicy, deadline time.Duration) *Listener {
+	listener := NewListener(openTestFile(t, createTestFile(t, "")), bufio.NewWriter(ioutil.Discard))
+	listener.SetOverflowPolicy(policy, deadline)
+
+	for len(listener.newDataNotifications) < cap(listener.newDataNotifications) {
+		listener.newDataNotifications <- newDataEvent{}
+	}
+
+	return listener
+}
+
+func TestOverflowCoalesce(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	listener := newOverflowingListener(t, OverflowCoalesce, 0)
+
+	s.notifyListener(listener)
+	s.notifyListener(listener)
+
+	if dropped := listener.DroppedNotifications(); dropped != 2 {
+		t.Errorf("listener dropped %d notifications, want 2", dropped)
+	}
+	if metrics := s.Metrics(); metrics.DroppedNotifications != 2 || metrics.TerminatedListeners != 0 {
+		t.Errorf("unexpected metrics %+v", metrics)
+	}
+	if listener.IsClosed() {
+		t.Error("listener was closed by coalesce policy")
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	listener := newOverflowingListener(t, OverflowBlock, time.Minute)
+
+	delivered := make(chan empty)
+	go func() {
+		s.notifyListener(listener)
+		close(delivered)
+	}()
+
+	<-listener.newDataNotifications // the reader catches up
+	<-delivered
+
+	if dropped := listener.DroppedNotifications(); dropped != 0 {
+		t.Errorf("listener dropped %d notifications, want 0", dropped)
+	}
+	if len(listener.newDataNotifications) != cap(listener.newDataNotifications) {
+		t.Error("blocked notification was not delivered")
+	}
+}
+
+func TestOverflowBlockDeadline(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	listener := newOverflowingListener(t, OverflowBlock, time.Millisecond)
+
+	s.notifyListener(listener)
+
+	if dropped := listener.DroppedNotifications(); dropped != 1 {
+		t.Errorf("listener dropped %d notifications, want 1", dropped)
+	}
+}
+
+func TestOverflowTerminate(t *testing.T) {
+	s := startTestStreamer(t)
+	listener := newOverflowingListener(t, OverflowTerminate, 0)
+
+	s.notifyListener(listener)
+
+	if !listener.IsClosed() {
+		t.Fatal("listener was not closed")
+	}
+	if err := s.StreamTo(listener, 0); err != ErrNotificationsOverflow {
+		t.Errorf("StreamTo() returned %v, want %v", err, ErrNotificationsOverflow)
+	}
+	if metrics := s.Metrics(); metrics.TerminatedListeners != 1 {
+		t.Errorf("%d listeners terminated, want 1", metrics.TerminatedListeners)
+	}
+}
+
+func TestActiveStreams(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "")
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(ioutil.Discard))
+
+	result := make(chan error)
+	go func() { result <- s.StreamTo(listener, 0) }()
+
+	var streams []StreamInfo
+	for i := 0; i < 1000 && len(streams) == 0; i++ {
+		streams = s.ActiveStreams()
+		time.Sleep(time.Millisecond)
+	}
+
+	if len(streams) != 1 || streams[0].File != name {
+		t.Errorf("active streams %+v, want the only stream of %s", streams, name)
+	}
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
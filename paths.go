@@ -0,0 +1,76 @@
+package file_streamer
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// PathNormalization defines how Streamer turns file names into keys of file change subscriptions.
+type PathNormalization uint8
+
+const (
+	// PathsCanonical makes all names of the same file (relative, absolute, through symlinks) to share one subscription:
+	// names are made absolute and symlinks are resolved. This is the default.
+	PathsCanonical PathNormalization = iota
+
+	// PathsCanonicalCaseFolded additionally folds the case of names. Use it on case-insensitive filesystems only
+	// (default ones of macOS and Windows): on case-sensitive ones different files would share a subscription.
+	PathsCanonicalCaseFolded
+
+	// PathsAsIs uses names as they were passed to os.Open(). Different names of the same file are different
+	// subscriptions then, and some of them may get no file change events.
+	PathsAsIs
+)
+
+// SetPathNormalization defines how names of files are normalized for subscriptions. See PathNormalization.
+//
+// Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetPathNormalization(mode PathNormalization) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.pathNormalization = mode
+	return nil
+}
+
+// normalizePath returns subscription key for <name>. Parts that can't be resolved (e.g. the file is removed already)
+// are left as is, so the result is still usable for lookups.
+func (s *Streamer) normalizePath(name string) string {
+	s.mu.Lock()
+	mode := s.pathNormalization
+	s.mu.Unlock()
+
+	if mode == PathsAsIs {
+		return name
+	}
+
+	path := canonicalPath(name)
+	if mode == PathsCanonicalCaseFolded {
+		path = strings.ToLower(path)
+	}
+
+	return path
+}
+
+// canonicalPath returns absolute path of <name> with symlinks resolved, as far as they can be resolved.
+func canonicalPath(name string) string {
+	path, err := filepath.Abs(name)
+	if err != nil {
+		path = filepath.Clean(name)
+	}
+
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	return path
+}
+
+// isInside tells whether <filePath> is <root> or is inside of it. Both paths must be canonical (see canonicalPath()).
+func isInside(root, filePath string) bool {
+	return filePath == root || strings.HasPrefix(filePath, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,81 @@
+package file_streamer
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPathNormalizationSharesSubscription(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "")
+
+	wd, _ := os.Getwd()
+	relative, err := filepath.Rel(wd, name)
+	if err != nil {
+		t.Skipf("no relative path to %s: %v", name, err)
+	}
+
+	symlink := name + ".link"
+	if err = os.Symlink(name, symlink); err != nil {
+		t.Skipf("can't create symlink: %v", err)
+	}
+	t.Cleanup(func() { os.Remove(symlink) })
+
+	var outputs []*syncBuffer
+	var listeners []*Listener
+	results := make(chan error, 3)
+	for _, path := range []string{name, relative, symlink} {
+		out := &syncBuffer{}
+		listener := NewListener(openTestFile(t, path), bufio.NewWriter(out))
+		outputs, listeners = append(outputs, out), append(listeners, listener)
+
+		go func() { results <- s.StreamTo(listener, 0) }()
+	}
+
+	for len(s.ActiveStreams()) != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	if files := countActiveFiles(s); files != 1 {
+		t.Errorf("%d subscriptions for the same file, want 1", files)
+	}
+
+	appendToFile(t, name, "new data")
+	for _, out := range outputs {
+		waitForOutput(t, out, "new data")
+	}
+
+	if closed := s.CloseStreamsFor(relative); closed != 3 {
+		t.Errorf("%d streams closed by relative name, want 3", closed)
+	}
+	for range listeners {
+		<-results
+	}
+}
+
+func countActiveFiles(s *Streamer) int {
+	files := make(map[string]empty)
+	s.callRouter(func() {
+		for file := range s.subscriptions {
+			files[file] = empty{}
+		}
+	})
+
+	return len(files)
+}
+
+func TestNormalizePathCaseFolding(t *testing.T) {
+	s := New(nil)
+	s.SetPathNormalization(PathsCanonicalCaseFolded)
+
+	if got, want := s.normalizePath("/NoN/ExIsTeNt"), "/non/existent"; got != filepath.FromSlash(want) {
+		t.Errorf("normalized to %q, want %q", got, want)
+	}
+
+	s.SetPathNormalization(PathsAsIs)
+	if got := s.normalizePath("./Log"); got != "./Log" {
+		t.Errorf("normalized to %q, want the name as is", got)
+	}
+}
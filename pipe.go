@@ -0,0 +1,29 @@
+package file_streamer
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+)
+
+// NewWriterListener creates Listener that streams data of <file> to any writer, e.g. to io.PipeWriter or to the stdin
+// of another process:
+//
+//	cmd := exec.Command("grep", "ERROR")
+//	stdin, _ := cmd.StdinPipe()
+//	cmd.Start()
+//	err := streamer.StreamTo(NewWriterListener(file, stdin), 0)
+//
+// When the reader of <w> goes away (the process exits or io.PipeReader is closed), the stream stops just like after
+// Listener.Close(): StreamTo() returns nil instead of EPIPE or io.ErrClosedPipe error. This holds for listeners
+// created with NewListener() as well.
+func NewWriterListener(file *os.File, w io.Writer) *Listener {
+	return NewListener(file, bufio.NewWriter(w))
+}
+
+// isClosedPipe reports whether <err> means the reader of data stream has gone.
+func isClosedPipe(err error) bool {
+	return errors.Is(err, io.ErrClosedPipe) || errors.Is(err, syscall.EPIPE)
+}
@@ -0,0 +1,77 @@
+package file_streamer
+
+import (
+	"io"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamToClosedPipe(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "first")
+
+	reader, writer := io.Pipe()
+	listener := NewWriterListener(openTestFile(t, name), writer)
+	events := listener.Events()
+
+	result := make(chan error, 1)
+	go func() { result <- s.StreamTo(listener, 0) }()
+
+	data := make([]byte, 5)
+	if _, err := io.ReadFull(reader, data); err != nil || string(data) != "first" {
+		t.Fatalf("read %q (%v), want %q", data, err, "first")
+	}
+	reader.Close()
+
+	waitSubscribed(t, s, listener)
+	appendToFile(t, name, "second")
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Errorf("stream to closed pipe ended with %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream to closed pipe did not stop")
+	}
+
+	for event := range events {
+		if event.Type == EventError {
+			t.Errorf("closed pipe was reported as error: %v", event.Err)
+		}
+	}
+}
+
+func TestStreamToProcess(t *testing.T) {
+	if _, err := exec.LookPath("head"); err != nil {
+		t.Skip("no 'head' utility")
+	}
+
+	s := startTestStreamer(t)
+	name := createTestFile(t, strings.Repeat("line\n", 100000))
+
+	cmd := exec.Command("head", "-n", "1")
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := &syncBuffer{}
+	cmd.Stdout = output
+	if err = cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 'head' exits after the first line, the rest of the file is written to the closed pipe
+	if err = s.StreamTo(NewWriterListener(openTestFile(t, name), stdin), 0); err != nil {
+		t.Errorf("stream to exited process ended with %v, want nil", err)
+	}
+
+	if err = cmd.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if output.String() != "line\n" {
+		t.Errorf("process got %q, want %q", output.String(), "line\n")
+	}
+}
@@ -0,0 +1,330 @@
+package file_streamer
+
+import (
+	"errors"
+	"github.com/fsnotify/fsnotify"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrPollWatcherClosed is returned by Add() of poll watcher after Close() call.
+var ErrPollWatcherClosed = errors.New("poll watcher is closed")
+
+// pollWatcher is a Watcher that detects changes by comparing results of os.Stat() of watched files every <interval>.
+type pollWatcher struct {
+	interval time.Duration
+
+	mu     sync.Mutex
+	files  map[string]os.FileInfo // the last seen state of watched files, nil value means 'file does not exist'
+	closed bool
+
+	events chan fsnotify.Event
+	errors chan error
+	done   chan empty
+}
+
+// NewPollWatcher returns WatcherFactory for watchers that stat watched files every <interval> and report changes of
+// their size or modification time as fsnotify.Write events. Replaced files are reported as fsnotify.Rename, removed
+// ones as fsnotify.Remove. Works on any file system, including NFS, where inotify does not see changes made on other
+// hosts, but the delay of notifications is up to <interval>.
+//
+// Only files are watched: changes inside watched directories are not reported.
+func NewPollWatcher(interval time.Duration) WatcherFactory {
+	return func() (Watcher, error) {
+		return newPollWatcher(interval), nil
+	}
+}
+
+func newPollWatcher(interval time.Duration) *pollWatcher {
+	w := &pollWatcher{
+		interval: interval,
+		files:    make(map[string]os.FileInfo),
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		done:     make(chan empty),
+	}
+	go w.run()
+
+	return w
+}
+
+func (w *pollWatcher) Add(name string) error {
+	info, err := os.Stat(name)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrPollWatcherClosed
+	}
+
+	w.files[name] = info
+	return nil
+}
+
+func (w *pollWatcher) Remove(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.files, name)
+	return nil
+}
+
+// watches tells whether <name> is watched by poll watcher.
+func (w *pollWatcher) watches(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, isWatched := w.files[name]
+	return isWatched
+}
+
+func (w *pollWatcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if !w.closed {
+		w.closed = true
+		close(w.done)
+	}
+
+	return nil
+}
+
+func (w *pollWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+func (w *pollWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *pollWatcher) run() {
+	defer close(w.errors)
+	defer close(w.events)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !w.poll() {
+				return
+			}
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// poll checks all watched files and reports their changes. Returns false when watcher was closed meanwhile.
+func (w *pollWatcher) poll() bool {
+	w.mu.Lock()
+	names := make([]string, 0, len(w.files))
+	for name := range w.files {
+		names = append(names, name)
+	}
+	w.mu.Unlock()
+
+	for _, name := range names {
+		op, changed := w.check(name)
+		if !changed {
+			continue
+		}
+
+		select {
+		case w.events <- fsnotify.Event{Name: name, Op: op}:
+		case <-w.done:
+			return false
+		}
+	}
+
+	return true
+}
+
+// check compares the current state of watched file <name> with the last seen one and remembers the current state.
+func (w *pollWatcher) check(name string) (op fsnotify.Op, changed bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	previous, isWatched := w.files[name]
+	if !isWatched {
+		return 0, false
+	}
+
+	// stat under the lock: the state must not be overwritten by an older result of concurrent check()
+	current, err := os.Stat(name)
+	if err != nil {
+		current = nil
+	}
+	w.files[name] = current
+
+	switch {
+	case previous == nil && current == nil:
+		return 0, false
+	case previous == nil:
+		return fsnotify.Create, true
+	case current == nil:
+		return fsnotify.Remove, true
+	case !os.SameFile(previous, current):
+		return fsnotify.Rename, true
+	case previous.Size() != current.Size() || !previous.ModTime().Equal(current.ModTime()):
+		return fsnotify.Write, true
+	}
+
+	return 0, false
+}
+
+// standbyWatcher runs poll watcher next to the primary one and reports events of both.
+type standbyWatcher struct {
+	primary Watcher
+	poll    *pollWatcher
+
+	// pollOnly, when set, splits files between watchers instead of watching each file with both of them: files it
+	// returns true for are watched by poll watcher alone, the others by primary watcher alone.
+	pollOnly func(name string) bool
+
+	events chan fsnotify.Event
+	errors chan error
+
+	mu       sync.Mutex
+	draining int // number of goroutines still writing to Events() and Errors()
+}
+
+// NewStandbyWatcher returns WatcherFactory for watchers that run <primary> watcher (usually NewFSNotifyWatcher) and
+// poll watcher (see NewPollWatcher) simultaneously, so a notification missed by <primary> (which happens with inotify
+// under heavy load or on NFS) delays the stream by <pollInterval> at most instead of stalling it until the next file
+// change.
+//
+// Notifications are de-duplicated: each <primary> event refreshes the state seen by poll watcher, so poll watcher
+// reports only changes made after the last <primary> event for the file. A file that <primary> watcher fails to watch
+// is watched by poll watcher alone, the failure is reported to Errors().
+func NewStandbyWatcher(primary WatcherFactory, pollInterval time.Duration) WatcherFactory {
+	return func() (Watcher, error) {
+		primaryWatcher, err := primary()
+		if err != nil {
+			return nil, err
+		}
+
+		return newStandbyWatcher(primaryWatcher, pollInterval, nil), nil
+	}
+}
+
+func newStandbyWatcher(primary Watcher, pollInterval time.Duration, pollOnly func(name string) bool) *standbyWatcher {
+	w := &standbyWatcher{
+		primary:  primary,
+		poll:     newPollWatcher(pollInterval),
+		pollOnly: pollOnly,
+		events:   make(chan fsnotify.Event),
+		errors:   make(chan error),
+		draining: 4,
+	}
+
+	go w.forwardPrimaryEvents()
+	go w.forwardEvents(w.poll.Events())
+	go w.forwardErrors(primary.Errors())
+	go w.forwardErrors(w.poll.Errors())
+
+	return w
+}
+
+func (w *standbyWatcher) Add(name string) error {
+	if w.pollOnly != nil {
+		if w.pollOnly(name) {
+			return w.poll.Add(name)
+		}
+		return w.primary.Add(name)
+	}
+
+	if err := w.poll.Add(name); err != nil {
+		return err
+	}
+
+	if err := w.primary.Add(name); err != nil {
+		w.reportError(err)
+	}
+
+	return nil
+}
+
+// reportError sends <err> to Errors() asynchronously: Add() caller may be the one who reads Errors().
+func (w *standbyWatcher) reportError(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.draining == 0 {
+		return // channels are closed already
+	}
+
+	w.draining++
+	go func() {
+		defer w.drained()
+		w.errors <- err
+	}()
+}
+
+func (w *standbyWatcher) Remove(name string) error {
+	polled := w.poll.watches(name)
+	w.poll.Remove(name)
+
+	if polled && w.pollOnly != nil {
+		return nil
+	}
+
+	return w.primary.Remove(name)
+}
+
+func (w *standbyWatcher) Close() error {
+	w.poll.Close()
+
+	return w.primary.Close()
+}
+
+func (w *standbyWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+func (w *standbyWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+func (w *standbyWatcher) forwardPrimaryEvents() {
+	defer w.drained()
+
+	for event := range w.primary.Events() {
+		w.poll.check(event.Name) // the change is reported already, poll watcher must not report it again
+		w.events <- event
+	}
+}
+
+func (w *standbyWatcher) forwardEvents(events <-chan fsnotify.Event) {
+	defer w.drained()
+
+	for event := range events {
+		w.events <- event
+	}
+}
+
+func (w *standbyWatcher) forwardErrors(errs <-chan error) {
+	defer w.drained()
+
+	for err := range errs {
+		w.errors <- err
+	}
+}
+
+// drained closes Events() and Errors() channels after all channels of primary and poll watchers are closed.
+func (w *standbyWatcher) drained() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.draining--; w.draining == 0 {
+		close(w.events)
+		close(w.errors)
+	}
+}
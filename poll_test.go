@@ -0,0 +1,174 @@
+package file_streamer
+
+import (
+	"bufio"
+	"github.com/fsnotify/fsnotify"
+	"io/ioutil"
+	"log"
+	"os"
+	"testing"
+	"time"
+)
+
+// silentWatcher is a primary watcher that misses all the events.
+type silentWatcher struct {
+	events chan fsnotify.Event
+	errors chan error
+}
+
+func newSilentWatcher() (Watcher, error) {
+	return &silentWatcher{events: make(chan fsnotify.Event), errors: make(chan error)}, nil
+}
+
+func (w *silentWatcher) Add(name string) error         { return nil }
+func (w *silentWatcher) Remove(name string) error      { return nil }
+func (w *silentWatcher) Events() <-chan fsnotify.Event { return w.events }
+func (w *silentWatcher) Errors() <-chan error          { return w.errors }
+
+func (w *silentWatcher) Close() error {
+	close(w.events)
+	close(w.errors)
+	return nil
+}
+
+func expectWatcherEvent(t *testing.T, watcher Watcher, name string, op fsnotify.Op) {
+	t.Helper()
+
+	select {
+	case event := <-watcher.Events():
+		if event.Name != name || event.Op != op {
+			t.Fatalf("got event %v, want %s of '%s'", event, op, name)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("no %s event of '%s'", op, name)
+	}
+}
+
+func expectNoWatcherEvents(t *testing.T, watcher Watcher, wait time.Duration) {
+	t.Helper()
+
+	select {
+	case event := <-watcher.Events():
+		t.Fatalf("unexpected event %v", event)
+	case <-time.After(wait):
+	}
+}
+
+func TestPollWatcher(t *testing.T) {
+	watcher, _ := NewPollWatcher(10 * time.Millisecond)()
+	defer watcher.Close()
+
+	name := createTestFile(t, "data")
+	if err := watcher.Add(name); err != nil {
+		t.Fatal(err)
+	}
+
+	appendToFile(t, name, "more data")
+	expectWatcherEvent(t, watcher, name, fsnotify.Write)
+	expectNoWatcherEvents(t, watcher, 50*time.Millisecond)
+
+	// the file is replaced by a new one
+	replacement := createTestFile(t, "new data")
+	if err := os.Rename(replacement, name); err != nil {
+		t.Fatal(err)
+	}
+	expectWatcherEvent(t, watcher, name, fsnotify.Rename)
+
+	os.Remove(name)
+	expectWatcherEvent(t, watcher, name, fsnotify.Remove)
+
+	if err := watcher.Add("/non/existent/file"); err == nil {
+		t.Error("non-existent file was added to poll watcher")
+	}
+}
+
+func TestStandbyWatcherMissedEvents(t *testing.T) {
+	watcher, _ := NewStandbyWatcher(newSilentWatcher, 10*time.Millisecond)()
+
+	name := createTestFile(t, "data")
+	if err := watcher.Add(name); err != nil {
+		t.Fatal(err)
+	}
+
+	appendToFile(t, name, "more data")
+	expectWatcherEvent(t, watcher, name, fsnotify.Write)
+
+	watcher.Close()
+	for range watcher.Events() {
+	}
+	for range watcher.Errors() {
+	}
+}
+
+func TestStandbyWatcherDeduplication(t *testing.T) {
+	watcher, err := NewStandbyWatcher(NewFSNotifyWatcher, 20*time.Millisecond)()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer watcher.Close()
+
+	name := createTestFile(t, "data")
+	if err := watcher.Add(name); err != nil {
+		t.Fatal(err)
+	}
+
+	appendToFile(t, name, "more data")
+	expectWatcherEvent(t, watcher, name, fsnotify.Write)
+	expectNoWatcherEvents(t, watcher, 100*time.Millisecond) // poll watcher does not repeat fsnotify event
+}
+
+func TestNetworkFSPolling(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	s.SetWatcherFactory(newSilentWatcher) // inotify does not see changes made by other NFS clients
+	if err := s.SetNetworkFSPollInterval(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	if err := s.SetNetworkFSPollInterval(time.Second); err != ErrRunning {
+		t.Errorf("poll interval change of running streamer returned %v, want %v", err, ErrRunning)
+	}
+
+	name := createTestFile(t, "")
+	if isNetworkFS(name) {
+		t.Skip("temporary directory is on network file system")
+	}
+
+	var out syncBuffer
+	writer := bufio.NewWriter(&out)
+	listener := NewListener(openTestFile(t, name), writer)
+	listener.SetNetworkFS(true)
+
+	result := make(chan error, 1)
+	go func() { result <- s.StreamTo(listener, 0) }()
+	waitSubscribed(t, s, listener)
+
+	appendToFile(t, name, "polled data")
+	waitForOutput(t, &out, "polled data")
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCustomWatcherIsNotWrapped(t *testing.T) {
+	var created Watcher
+	s := New(log.New(ioutil.Discard, "", 0))
+	s.SetWatcherFactory(func() (Watcher, error) {
+		watcher, err := newSilentWatcher()
+		created = watcher
+		return watcher, err
+	})
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Stop()
+
+	if s.fsNotify != created {
+		t.Errorf("custom watcher is wrapped into %T", s.fsNotify)
+	}
+}
@@ -0,0 +1,60 @@
+package file_streamer
+
+import "strconv"
+
+// Priority is the class of service of a stream: when file reads are limited (see Streamer.SetReadConcurrency()),
+// streams of higher priority get their turns first, so incident-response tails are not slowed down by bulk exports.
+type Priority int8
+
+const (
+	PriorityLow    Priority = -1 // bulk exports and other streams that can wait
+	PriorityNormal Priority = 0  // the default
+	PriorityHigh   Priority = 1  // interactive tails that must not wait behind the others
+
+	priorities = int(PriorityHigh-PriorityLow) + 1
+)
+
+// String returns 'low', 'normal' or 'high'.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+
+	return "Priority(" + strconv.Itoa(int(p)) + ")"
+}
+
+// index returns the index of queue of priority in readLimiter, unknown priorities are clamped to known ones.
+func (p Priority) index() int {
+	if p < PriorityLow {
+		p = PriorityLow
+	}
+	if p > PriorityHigh {
+		p = PriorityHigh
+	}
+
+	return int(p - PriorityLow)
+}
+
+// SetPriority sets the priority of Listener's stream, PriorityNormal by default. Under contention for file reads
+// (see Streamer.SetReadConcurrency()) streams of higher priority are served first, streams of lower priority wait
+// until no stream of higher priority needs to read.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetPriority(priority Priority) {
+	bs.mu.Lock()
+	bs.priority = priority
+	bs.mu.Unlock()
+}
+
+// Priority returns the priority of Listener's stream.
+func (bs *Listener) Priority() Priority {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	return bs.priority
+}
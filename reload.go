@@ -0,0 +1,149 @@
+package file_streamer
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// Reloader is a server component that re-reads its configuration without dropping active streams.
+type Reloader interface {
+	Reload() error
+}
+
+// ReloaderFunc is an adapter to allow the use of ordinary functions as reloaders.
+type ReloaderFunc func() error
+
+// Reload calls f().
+func (f ReloaderFunc) Reload() error {
+	return f()
+}
+
+// ReloadOnSIGHUP calls Reload() of all <reloaders> each time the process receives SIGHUP, until <ctx> is done.
+// Reload errors are logged with <logger>: the old configuration stays active then. It blocks, so run it in a
+// separate goroutine.
+func ReloadOnSIGHUP(ctx context.Context, logger *log.Logger, reloaders ...Reloader) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	reloadOnSignals(ctx, logger, signals, reloaders)
+}
+
+func reloadOnSignals(ctx context.Context, logger *log.Logger, signals <-chan os.Signal, reloaders []Reloader) {
+	for {
+		select {
+		case <-signals:
+			for _, reloader := range reloaders {
+				if err := reloader.Reload(); err != nil {
+					logger.Printf("Configuration reload error: %s", err.Error())
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// CertificateReloader keeps TLS certificate loaded from files and replaces it on Reload(), so renewed certificates
+// are used for new connections while established ones keep streaming.
+// Use its GetCertificate method as tls.Config.GetCertificate.
+type CertificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mu   sync.RWMutex
+	cert *tls.Certificate
+}
+
+// NewCertificateReloader loads the certificate from PEM encoded <certFile> and <keyFile>.
+func NewCertificateReloader(certFile, keyFile string) (*CertificateReloader, error) {
+	r := &CertificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Reload loads the certificate from files again. The previous certificate is kept when files can't be loaded.
+func (r *CertificateReloader) Reload() error {
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.mu.Unlock()
+
+	return nil
+}
+
+// GetCertificate returns the current certificate.
+func (r *CertificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.cert, nil
+}
+
+// HandlerConfigLoader returns the current root directory and options of ReloadableHandler.
+type HandlerConfigLoader func() (root string, options HandlerOptions, err error)
+
+// ReloadableHandler is Handler which root directory and options are replaced on Reload(): new requests use the new
+// configuration, while active streams keep the one they have started with.
+//
+//	handler, err := file_streamer.NewReloadableHandler(streamer, loadConfig)
+//	...
+//	go file_streamer.ReloadOnSIGHUP(ctx, logger, handler, certificates)
+type ReloadableHandler struct {
+	streamer *Streamer
+	load     HandlerConfigLoader
+
+	lineCounts *LineCountCache // shared by all configurations, line counts don't depend on them
+
+	mu      sync.RWMutex
+	handler *streamHandler
+}
+
+// NewReloadableHandler creates ReloadableHandler with the configuration returned by <load>, which is called again on
+// each Reload().
+func NewReloadableHandler(streamer *Streamer, load HandlerConfigLoader) (*ReloadableHandler, error) {
+	h := &ReloadableHandler{streamer: streamer, load: load, lineCounts: NewLineCountCache()}
+	if err := h.Reload(); err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Reload loads the configuration again. The previous configuration is kept when it can't be loaded.
+func (h *ReloadableHandler) Reload() error {
+	root, options, err := h.load()
+	if err != nil {
+		return err
+	}
+
+	handler := &streamHandler{streamer: h.streamer, root: root, options: options, lineCounts: h.lineCounts}
+
+	h.mu.Lock()
+	h.handler = handler
+	h.mu.Unlock()
+
+	return nil
+}
+
+// ServeHTTP serves the request with the current configuration, see Handler().
+func (h *ReloadableHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.mu.RLock()
+	handler := h.handler
+	h.mu.RUnlock()
+
+	handler.ServeHTTP(w, req)
+}
@@ -0,0 +1,182 @@
+package file_streamer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// writeTestCertificate writes self-signed certificate for <commonName> into <certFile> and <keyFile>.
+func writeTestCertificate(t *testing.T, certFile, keyFile, commonName string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+}
+
+func certificateName(t *testing.T, r *CertificateReloader) string {
+	cert, _ := r.GetCertificate(nil)
+	parsed, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return parsed.Subject.CommonName
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file-streamer-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	writeTestCertificate(t, certFile, keyFile, "old")
+
+	reloader, err := NewCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writeTestCertificate(t, certFile, keyFile, "new")
+	if err = reloader.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if name := certificateName(t, reloader); name != "new" {
+		t.Errorf("certificate for %q after reload, want %q", name, "new")
+	}
+
+	ioutil.WriteFile(certFile, []byte("broken"), 0600)
+	if err = reloader.Reload(); err == nil {
+		t.Error("broken certificate was loaded")
+	}
+	if name := certificateName(t, reloader); name != "new" {
+		t.Errorf("certificate for %q after failed reload, want the previous one", name)
+	}
+}
+
+func TestReloadOnSignals(t *testing.T) {
+	var reloads int
+	failing := ReloaderFunc(func() error { return errors.New("broken config") })
+	counting := ReloaderFunc(func() error {
+		reloads++
+		return nil
+	})
+
+	signals := make(chan os.Signal)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan empty)
+	go func() {
+		reloadOnSignals(ctx, log.New(ioutil.Discard, "", 0), signals, []Reloader{failing, counting})
+		close(done)
+	}()
+
+	signals <- syscall.SIGHUP
+	signals <- syscall.SIGHUP
+	cancel()
+	<-done
+
+	if reloads != 2 {
+		t.Errorf("reloaded %d times, want 2: errors of other reloaders must not stop reload", reloads)
+	}
+}
+
+func tempRoot(t *testing.T) string {
+	root, err := ioutil.TempDir("", "reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(root) })
+
+	return root
+}
+
+func TestReloadableHandler(t *testing.T) {
+	oldRoot, newRoot := tempRoot(t), tempRoot(t)
+	writeRootFile(t, oldRoot, "app.log", "old\n")
+	writeRootFile(t, newRoot, "app.log", "new\n")
+
+	var mu sync.Mutex
+	root := oldRoot
+	handler, err := NewReloadableHandler(startTestStreamer(t), func() (string, HandlerOptions, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		return root, HandlerOptions{Mode: ModeHTTP, Timeout: 5 * time.Second}, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	active, err := http.Get(server.URL + "/app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer active.Body.Close()
+	readAtLeast(t, active.Body, "old\n")
+
+	mu.Lock()
+	root = newRoot
+	mu.Unlock()
+
+	signals := make(chan os.Signal)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan empty)
+	go func() {
+		reloadOnSignals(ctx, log.New(ioutil.Discard, "", 0), signals, []Reloader{handler})
+		close(done)
+	}()
+	signals <- syscall.SIGHUP
+	cancel()
+	<-done
+
+	reloaded, err := http.Get(server.URL + "/app.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reloaded.Body.Close()
+	readAtLeast(t, reloaded.Body, "new\n")
+
+	// the active stream is not dropped and keeps following the old file
+	appendToFile(t, filepath.Join(oldRoot, "app.log"), "still old\n")
+	readAtLeast(t, active.Body, "still old\n")
+}
@@ -0,0 +1,145 @@
+package file_streamer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+// ResumeType is the value of ResumeRequest.Type, which distinguishes resume requests from other messages of a
+// transport.
+const ResumeType = "resume"
+
+// Resume requests can't hash more than this number of bytes, and resume points are looked for this far from the
+// approximate offset by default.
+const (
+	maxResumeLength     = 1024 * 1024
+	DefaultResumeWindow = 64 * 1024
+)
+
+// rolling hash base: a large odd number, so every byte affects all bits of the hash
+const resumeHashBase = 1099511628211
+
+var (
+	// ErrInvalidResumeRequest is returned by FindResumeOffset() for requests with malformed hash or length.
+	ErrInvalidResumeRequest = errors.New("invalid resume request")
+
+	// ErrResumePointNotFound is returned by FindResumeOffset() when the file has no data matching the request near
+	// the approximate offset.
+	ErrResumePointNotFound = errors.New("resume point not found")
+)
+
+// ResumeRequest is sent by a reconnecting client that is not sure about exact offset of data it has, for example after
+// log rotation ambiguity. The server looks for the last <Length> bytes of client's data near <Offset> and resumes the
+// stream right after them, so no lines are duplicated or missed (see FindResumeOffset()).
+type ResumeRequest struct {
+	Type   string `json:"type"`
+	Offset int64  `json:"offset"` // approximate offset of the end of client's data
+	Length int    `json:"length"` // the number of hashed bytes at the end of client's data
+	Hash   string `json:"hash"`   // ResumeHash() of these bytes
+}
+
+// NewResumeRequest returns ResumeRequest for a client, that has <data> ending at approximately <offset> of the file.
+// Only the last <length> bytes of <data> are hashed: a few lines are usually enough to find the exact position.
+func NewResumeRequest(data []byte, offset int64, length int) ResumeRequest {
+	if length > len(data) {
+		length = len(data)
+	}
+
+	tail := data[len(data)-length:]
+	return ResumeRequest{Type: ResumeType, Offset: offset, Length: length, Hash: ResumeHash(tail)}
+}
+
+// ResumeHash returns the hash of <data> for ResumeRequest: 64-bit polynomial rolling hash in hex, which lets the server
+// check all the positions near the approximate offset in a single pass.
+func ResumeHash(data []byte) string {
+	return fmt.Sprintf("%016x", rollingHash(data))
+}
+
+func rollingHash(data []byte) uint64 {
+	var hash uint64
+	for _, c := range data {
+		hash = hash*resumeHashBase + uint64(c)
+	}
+
+	return hash
+}
+
+// FindResumeOffset returns the offset to resume streaming of <file> for <request>: the end of the data with the hash
+// from <request>, which is the closest to the approximate offset among the ones not further than <window> bytes from
+// it. Zero <window> means DefaultResumeWindow. The position of <file> is not changed.
+//
+// Returns ErrResumePointNotFound when there is no such data in the window.
+func FindResumeOffset(file *os.File, request ResumeRequest, window int64) (int64, error) {
+	want, err := strconv.ParseUint(request.Hash, 16, 64)
+	if err != nil || request.Length <= 0 || request.Length > maxResumeLength || request.Offset < 0 {
+		return 0, ErrInvalidResumeRequest
+	}
+
+	if window <= 0 {
+		window = DefaultResumeWindow
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	length := int64(request.Length)
+	start := request.Offset - window - length
+	if start < 0 {
+		start = 0
+	}
+	end := request.Offset + window
+	if end > info.Size() {
+		end = info.Size()
+	}
+	if end-start < length {
+		return 0, ErrResumePointNotFound
+	}
+
+	data := make([]byte, end-start)
+	n, err := file.ReadAt(data, start)
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+	data = data[:n]
+	if len(data) < request.Length {
+		return 0, ErrResumePointNotFound
+	}
+
+	// highest power of base, to remove the byte leaving the window from the hash
+	var highest uint64 = 1
+	for i := 1; i < request.Length; i++ {
+		highest *= resumeHashBase
+	}
+
+	found, bestDistance := int64(-1), int64(0)
+	hash := rollingHash(data[:request.Length])
+	for i := request.Length; i <= len(data); i++ {
+		if i > request.Length {
+			hash = (hash-uint64(data[i-request.Length-1])*highest)*resumeHashBase + uint64(data[i-1])
+		}
+
+		if hash != want {
+			continue
+		}
+
+		candidate := start + int64(i)
+		distance := candidate - request.Offset
+		if distance < 0 {
+			distance = -distance
+		}
+		if distance <= window && (found < 0 || distance < bestDistance) {
+			found, bestDistance = candidate, distance
+		}
+	}
+
+	if found < 0 {
+		return 0, ErrResumePointNotFound
+	}
+
+	return found, nil
+}
@@ -0,0 +1,71 @@
+package file_streamer
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestFindResumeOffset(t *testing.T) {
+	data := "first line\nsecond line\nthird line\nsecond line\nfourth line\n"
+	file := openTestFile(t, createTestFile(t, data))
+
+	// the client has data up to the second 'second line', but its offset is a bit off
+	exact := int64(strings.LastIndex(data, "fourth"))
+	request := NewResumeRequest([]byte(data[:exact]), exact+3, 12)
+
+	offset, err := FindResumeOffset(file, request, 100)
+	if err != nil || offset != exact {
+		t.Errorf("got resume offset %d (%v), want %d", offset, err, exact)
+	}
+
+	// the closest match wins: the first 'second line' is closer to the approximate offset
+	first := int64(strings.Index(data, "third"))
+	request.Offset = first
+	if offset, err = FindResumeOffset(file, request, 100); err != nil || offset != first {
+		t.Errorf("got resume offset %d (%v), want %d", offset, err, first)
+	}
+
+	// the window is too small to reach the data
+	request.Offset = 0
+	if _, err = FindResumeOffset(file, request, 5); err != ErrResumePointNotFound {
+		t.Errorf("search out of window returned %v, want %v", err, ErrResumePointNotFound)
+	}
+
+	request = NewResumeRequest([]byte("missing line\n"), exact, 100)
+	if _, err = FindResumeOffset(file, request, 100); err != ErrResumePointNotFound {
+		t.Errorf("search of missing data returned %v, want %v", err, ErrResumePointNotFound)
+	}
+
+	request.Hash = "not a hash"
+	if _, err = FindResumeOffset(file, request, 100); err != ErrInvalidResumeRequest {
+		t.Errorf("search with invalid hash returned %v, want %v", err, ErrInvalidResumeRequest)
+	}
+}
+
+func TestHandlerResume(t *testing.T) {
+	server, root := startTestServer(t, DefaultServerConfig())
+	writeRootFile(t, root, "app.log", "one\ntwo\nthree\n")
+
+	request := NewResumeRequest([]byte("one\ntwo\n"), 6, 4)
+	url := fmt.Sprintf("%s/log-stream/app.log?offset=%d&resume_length=%d&resume_hash=%s",
+		server.URL, request.Offset, request.Length, request.Hash)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	readAtLeast(t, resp.Body, "three\n")
+
+	missing := NewResumeRequest([]byte("four\n"), 6, 5)
+	resp, err = http.Get(fmt.Sprintf("%s/log-stream/app.log?offset=6&resume_length=5&resume_hash=%s", server.URL, missing.Hash))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("got status %d for missing resume point, want %d", resp.StatusCode, http.StatusConflict)
+	}
+}
@@ -0,0 +1,106 @@
+package file_streamer
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy defines how Streamer retries file reads failed with permission errors, which happen on network file
+// systems when external tooling (e.g. log rotation) briefly changes file mode. NewRetryWriter() uses it for transient
+// write errors.
+type RetryPolicy struct {
+	MaxAttempts int           // retries in a row before the stream fails, 0 means 'no retries'
+	MinBackoff  time.Duration // delay before the first retry, 100ms by default
+	MaxBackoff  time.Duration // delay doubles on each retry up to this limit, 10 seconds by default
+}
+
+// SetRetryPolicy makes Streamer to retry reads of the file failed with permission errors according to <policy>.
+// Each failure is reported with EventPermissionDenied event (see SetEventHandler), the stream fails when all attempts
+// are exhausted. A successful read resets the attempts counter.
+//
+// Should be called before passing Listener to Streamer.StreamTo().
+func (bs *Listener) SetRetryPolicy(policy RetryPolicy) {
+	bs.mu.Lock()
+	bs.retryPolicy = policy.withDefaults()
+	bs.mu.Unlock()
+}
+
+// withDefaults returns the policy with default values of unset backoff limits.
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MinBackoff <= 0 {
+		p.MinBackoff = 100 * time.Millisecond
+	}
+	if p.MaxBackoff < p.MinBackoff {
+		p.MaxBackoff = 10 * time.Second
+	}
+
+	return p
+}
+
+// backoff returns delay before retry number <attempt> (starting from 0).
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.MinBackoff
+	for i := 0; i < attempt && delay < p.MaxBackoff; i++ {
+		delay *= 2
+	}
+
+	if delay > p.MaxBackoff {
+		delay = p.MaxBackoff
+	}
+	return delay
+}
+
+func isPermissionError(err error) bool {
+	return errors.Is(err, os.ErrPermission)
+}
+
+type retryWriter struct {
+	w           io.Writer
+	policy      RetryPolicy
+	isTransient func(err error) bool
+}
+
+// NewRetryWriter returns a writer that retries writes to <w> failed with transient errors according to <policy>,
+// instead of failing the stream immediately. <isTransient> classifies errors, nil means IsTransientWriteError. Data
+// written before the failure is not written again: a retry writes the rest of data only. The last error is returned
+// when all attempts are exhausted.
+//
+// Wrap the destination of Listener's buffered writer with it:
+//
+//	listener := file_streamer.NewListener(file, bufio.NewWriter(file_streamer.NewRetryWriter(conn, policy, nil)))
+func NewRetryWriter(w io.Writer, policy RetryPolicy, isTransient func(err error) bool) io.Writer {
+	if isTransient == nil {
+		isTransient = IsTransientWriteError
+	}
+
+	return &retryWriter{w: w, policy: policy.withDefaults(), isTransient: isTransient}
+}
+
+func (r *retryWriter) Write(p []byte) (int, error) {
+	written := 0
+	for attempt := 0; ; attempt++ {
+		n, err := r.w.Write(p[written:])
+		written += n
+
+		if err == nil || attempt >= r.policy.MaxAttempts || !r.isTransient(err) {
+			return written, err
+		}
+
+		time.Sleep(r.policy.backoff(attempt))
+	}
+}
+
+// IsTransientWriteError tells whether write failed with <err> may succeed when retried: write timeouts (including
+// the ones of WebSocket connections), EAGAIN of non-blocking pipes and interrupted system calls.
+func IsTransientWriteError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) || errors.Is(err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,88 @@
+package file_streamer
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	listener := NewListener(nil, nil)
+	listener.SetRetryPolicy(RetryPolicy{MaxAttempts: 5, MaxBackoff: 500 * time.Millisecond})
+
+	policy := listener.retryPolicy
+	want := []time.Duration{100, 200, 400, 500, 500}
+	for attempt, delay := range want {
+		if got := policy.backoff(attempt); got != delay*time.Millisecond {
+			t.Errorf("attempt %d backoff is %s, want %s", attempt, got, delay*time.Millisecond)
+		}
+	}
+}
+
+func TestIsPermissionError(t *testing.T) {
+	readErr := &os.PathError{Op: "read", Path: "/var/log/app.log", Err: syscall.EACCES}
+	if !isPermissionError(readErr) {
+		t.Errorf("%v is not detected as permission error", readErr)
+	}
+
+	if isPermissionError(&os.PathError{Op: "read", Path: "/var/log/app.log", Err: syscall.EIO}) {
+		t.Error("EIO is detected as permission error")
+	}
+}
+
+type flakyWriter struct {
+	failures int
+	err      error
+	written  bytes.Buffer
+	calls    int
+}
+
+func (w *flakyWriter) Write(p []byte) (int, error) {
+	w.calls++
+	if w.failures > 0 {
+		w.failures--
+		// half of data is written before the failure
+		n := len(p) / 2
+		w.written.Write(p[:n])
+		return n, w.err
+	}
+
+	return w.written.Write(p)
+}
+
+func TestRetryWriter(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}
+
+	flaky := &flakyWriter{failures: 2, err: syscall.EAGAIN}
+	n, err := NewRetryWriter(flaky, policy, nil).Write([]byte("0123456789"))
+	if err != nil || n != 10 {
+		t.Fatalf("write returned %d, %v; want 10, nil", n, err)
+	}
+	if got := flaky.written.String(); got != "0123456789" {
+		t.Errorf("written data is %q, want %q", got, "0123456789")
+	}
+
+	exhausted := &flakyWriter{failures: 10, err: os.ErrDeadlineExceeded}
+	if _, err = NewRetryWriter(exhausted, policy, nil).Write([]byte("data")); err != os.ErrDeadlineExceeded {
+		t.Errorf("write returned %v after exhausted attempts, want %v", err, os.ErrDeadlineExceeded)
+	}
+	if exhausted.calls != 4 {
+		t.Errorf("writer was called %d times, want 4", exhausted.calls)
+	}
+
+	permanent := &flakyWriter{failures: 1, err: syscall.EPIPE}
+	if _, err = NewRetryWriter(permanent, policy, nil).Write([]byte("data")); err != syscall.EPIPE {
+		t.Errorf("write returned %v, want %v", err, syscall.EPIPE)
+	}
+	if permanent.calls != 1 {
+		t.Errorf("permanent error was retried: writer was called %d times", permanent.calls)
+	}
+
+	classified := &flakyWriter{failures: 1, err: syscall.EPIPE}
+	isTransient := func(err error) bool { return err == syscall.EPIPE }
+	if _, err = NewRetryWriter(classified, policy, isTransient).Write([]byte("data")); err != nil {
+		t.Errorf("write with custom classifier returned %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"regexp"
+)
+
+// Search returns this many matches at most by default.
+const defaultMaxMatches = 100
+
+// Search keeps this many bytes of each line by default.
+const defaultMaxLineLength = 64 * 1024
+
+// SearchOptions configures Search().
+type SearchOptions struct {
+	Offset     int64 // where to start scanning: lines starting before it are not matched
+	Before     int   // the number of context lines before each match
+	After      int   // the number of context lines after each match
+	MaxMatches int   // stop scanning after this many matches, 100 by default
+
+	// MaxLineLength limits memory used for a line, 64KB by default: longer lines are matched and returned truncated.
+	MaxLineLength int
+}
+
+// SearchMatch is a line matched by Search(). Offsets point to the beginnings of lines, so a stream started at
+// Offset (and EndOffset) shows the matched line (or what follows it) first.
+type SearchMatch struct {
+	Offset    int64    `json:"offset"`
+	EndOffset int64    `json:"end_offset"` // the offset of the line after the matched one
+	Line      string   `json:"line"`
+	Before    []string `json:"before,omitempty"`
+	After     []string `json:"after,omitempty"`
+}
+
+// Search scans the file at <path> for lines matching regular expression <pattern> (see regexp.Compile) and returns
+// them with context lines and offsets, so UI can offer 'find in log, then start live stream from that position'.
+// Only the data already written to the file is scanned.
+func Search(path, pattern string, options SearchOptions) ([]SearchMatch, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	return SearchRegexp(path, re, options)
+}
+
+// SearchRegexp is Search() with compiled regular expression <re>.
+func SearchRegexp(path string, re *regexp.Regexp, options SearchOptions) ([]SearchMatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if _, err = file.Seek(options.Offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if options.MaxMatches <= 0 {
+		options.MaxMatches = defaultMaxMatches
+	}
+	if options.MaxLineLength <= 0 {
+		options.MaxLineLength = defaultMaxLineLength
+	}
+
+	var (
+		matches  []SearchMatch
+		previous []string // up to options.Before last lines
+		waiting  []int    // indexes of matches still collecting lines after them
+	)
+
+	offset := options.Offset
+	reader := bufio.NewReader(file)
+	for {
+		data, size, err := readLine(reader, options.MaxLineLength)
+		if size == 0 && err != nil {
+			if err == io.EOF {
+				return matches, nil
+			}
+			return matches, err
+		}
+
+		lineOffset := offset
+		offset += size
+		line := string(bytes.TrimRight(data, "\r\n"))
+
+		still := waiting[:0]
+		for _, i := range waiting {
+			matches[i].After = append(matches[i].After, line)
+			if len(matches[i].After) < options.After {
+				still = append(still, i)
+			}
+		}
+		waiting = still
+
+		if len(matches) < options.MaxMatches && re.MatchString(line) {
+			matches = append(matches, SearchMatch{
+				Offset:    lineOffset,
+				EndOffset: offset,
+				Line:      line,
+				Before:    append([]string(nil), previous...),
+			})
+			if options.After > 0 {
+				waiting = append(waiting, len(matches)-1)
+			}
+		}
+
+		if len(matches) == options.MaxMatches && len(waiting) == 0 {
+			return matches, nil
+		}
+
+		if options.Before > 0 {
+			if len(previous) == options.Before {
+				previous = previous[1:]
+			}
+			previous = append(previous, line)
+		}
+
+		if err == io.EOF {
+			return matches, nil // the last line has no line feed
+		}
+		if err != nil {
+			return matches, err
+		}
+	}
+}
+
+// readLine reads the next line from <reader>, keeping at most <limit> bytes of it: the rest of a longer line is
+// skipped. Returns the kept data and the size of the whole line.
+func readLine(reader *bufio.Reader, limit int) ([]byte, int64, error) {
+	var (
+		line []byte
+		size int64
+	)
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		size += int64(len(chunk))
+
+		if keep := limit - len(line); keep > 0 {
+			if keep > len(chunk) {
+				keep = len(chunk)
+			}
+			line = append(line, chunk[:keep]...)
+		}
+
+		if err != bufio.ErrBufferFull {
+			return line, size, err
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package file_streamer
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	name := createTestFile(t, "one\nerror: two\nthree\nfour\nerror: five\nsix")
+
+	matches, err := Search(name, "^error", SearchOptions{Before: 1, After: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []SearchMatch{
+		{Offset: 4, EndOffset: 15, Line: "error: two", Before: []string{"one"}, After: []string{"three", "four"}},
+		{Offset: 26, EndOffset: 38, Line: "error: five", Before: []string{"four"}, After: []string{"six"}},
+	}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("got matches %+v, want %+v", matches, want)
+	}
+
+	// scanning from the middle of file, limited by the number of matches
+	matches, err = Search(name, "e", SearchOptions{Offset: 15, MaxMatches: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 2 || matches[0].Line != "three" || matches[1].Line != "error: five" {
+		t.Errorf("unexpected matches %+v", matches)
+	}
+
+	if _, err = Search(name, "(", SearchOptions{}); err == nil {
+		t.Error("search with invalid pattern succeeded")
+	}
+}
+
+func TestSearchLongLines(t *testing.T) {
+	long := strings.Repeat("x", 10000) + "error"
+	name := createTestFile(t, long+"\nerror: next\n")
+
+	matches, err := Search(name, "error", SearchOptions{MaxLineLength: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the end of the long line is not kept, but offsets count all of its bytes
+	want := []SearchMatch{{Offset: int64(len(long)) + 1, EndOffset: int64(len(long)) + 13, Line: "error: next"}}
+	if !reflect.DeepEqual(matches, want) {
+		t.Errorf("got matches %+v, want %+v", matches, want)
+	}
+}
+
+func TestHandlerSearch(t *testing.T) {
+	server, root := startTestServer(t, DefaultServerConfig())
+	writeRootFile(t, root, "app.log", "started\npanic: oops\nstopped\n")
+
+	resp, err := http.Get(server.URL + "/log-stream/app.log?search=panic&context=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var matches []SearchMatch
+	if err = json.NewDecoder(resp.Body).Decode(&matches); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matches) != 1 || matches[0].Offset != 8 || len(matches[0].Before) != 1 || len(matches[0].After) != 1 {
+		t.Errorf("unexpected matches %+v", matches)
+	}
+
+	// huge limits are clamped
+	resp, err = http.Get(server.URL + "/log-stream/app.log?search=panic&context=1000000000&max=1000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("got status %d for huge limits, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	resp, err = http.Get(server.URL + "/log-stream/app.log?search=%28")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("got status %d for invalid pattern, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,96 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// firstWriteWriter closes <written> on the first write.
+type firstWriteWriter struct {
+	once    sync.Once
+	written chan empty
+}
+
+func (w *firstWriteWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.written) })
+	return len(p), nil
+}
+
+// Listener.Close() used to close the channel the events router writes notifications to.
+func TestCloseWhileFileChanges(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "data\n")
+
+	for i := 0; i < 20; i++ {
+		out := &firstWriteWriter{written: make(chan empty)}
+		listener := NewListener(openTestFile(t, name), bufio.NewWriter(out))
+
+		streamDone := make(chan error, 1)
+		go func() { streamDone <- s.StreamTo(listener, 0) }()
+		<-out.written
+
+		writerDone := make(chan empty)
+		go func() {
+			defer close(writerDone)
+
+			f, err := os.OpenFile(name, os.O_APPEND|os.O_WRONLY, 0)
+			if err != nil {
+				return
+			}
+			defer f.Close()
+
+			for j := 0; j < 100; j++ {
+				f.WriteString("line\n")
+			}
+		}()
+
+		listener.Close()
+		if err := <-streamDone; err != nil {
+			t.Fatal(err)
+		}
+		<-writerDone
+	}
+}
+
+// Stop() finishes only after all streams are finished, closing the watcher after the last subscription is removed.
+func TestStopWaitsForActiveStreams(t *testing.T) {
+	s := New(log.New(ioutil.Discard, "", 0))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &firstWriteWriter{written: make(chan empty)}
+	listener := NewListener(openTestFile(t, createTestFile(t, "data")), bufio.NewWriter(out))
+
+	streamDone := make(chan error, 1)
+	go func() { streamDone <- s.StreamTo(listener, 0) }()
+	<-out.written
+
+	stopDone := make(chan error, 1)
+	go func() { stopDone <- s.Stop() }()
+
+	select {
+	case <-stopDone:
+		t.Fatal("Stop() returned while the stream is active")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	listener.Close()
+	if err := <-streamDone; err != nil {
+		t.Errorf("stream finished with %v", err)
+	}
+
+	select {
+	case err := <-stopDone:
+		if err != nil {
+			t.Errorf("Stop() returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Stop() did not return after the last stream finished")
+	}
+}
@@ -0,0 +1,103 @@
+package file_streamer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// HTTP headers set by HTTP sink for each batch
+const (
+	HeaderFile   = "X-File-Streamer-File"
+	HeaderOffset = "X-File-Streamer-Offset"
+)
+
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPSink creates a Sink that POSTs each batch to <url>. The file path and the offset of the batch are sent in
+// HeaderFile and HeaderOffset headers. Any response status other than 2xx is considered a delivery failure.
+//
+// <client> may be nil, http.DefaultClient is used then.
+func NewHTTPSink(url string, client *http.Client) Sink {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &httpSink{url: url, client: client}
+}
+
+func (s *httpSink) Send(ctx context.Context, batch Batch) error {
+	req, err := http.NewRequest(http.MethodPost, s.url, bytes.NewReader(batch.Data))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set(HeaderFile, batch.File)
+	req.Header.Set(HeaderOffset, strconv.FormatInt(batch.Offset, 10))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("upstream responded with %s", resp.Status)
+	}
+
+	return nil
+}
+
+type tcpSink struct {
+	addr    string
+	timeout time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewTCPSink creates a Sink that writes raw file data into a TCP connection to <addr>. The connection is established
+// on the first batch and re-established after write errors. <timeout> limits both dialing and writing of each batch,
+// 0 means 'no timeout'.
+//
+// Data of all files is written into the same connection, so use it for a single file or for line-oriented data
+// collected by line-aware receivers.
+func NewTCPSink(addr string, timeout time.Duration) Sink {
+	return &tcpSink{addr: addr, timeout: timeout}
+}
+
+func (s *tcpSink) Send(ctx context.Context, batch Batch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: s.timeout}
+		conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	if s.timeout != 0 {
+		_ = s.conn.SetWriteDeadline(time.Now().Add(s.timeout))
+	}
+
+	if _, err := s.conn.Write(batch.Data); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// sseWriter sends each complete line of file data as a Server-Sent Event. Event ID is the file offset right after the
+// line, so a reconnecting EventSource continues from the next line (see Last-Event-ID header).
+type sseWriter struct {
+	w       io.Writer
+	offset  int64  // file offset of partial[0]
+	partial []byte // the beginning of the line not finished yet
+	event   []byte // reusable buffer for event
+}
+
+func (s *sseWriter) Write(p []byte) (int, error) {
+	written := len(p)
+
+	for {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			s.partial = append(s.partial, p...)
+			return written, nil
+		}
+
+		line := p[:i]
+		if len(s.partial) != 0 {
+			line = append(s.partial, line...)
+		}
+		s.offset += int64(len(line) + 1)
+
+		s.event = append(s.event[:0], "id: "...)
+		s.event = strconv.AppendInt(s.event, s.offset, 10)
+		s.event = append(s.event, "\ndata: "...)
+		s.event = append(s.event, bytes.TrimSuffix(line, []byte{'\r'})...)
+		s.event = append(s.event, "\n\n"...)
+
+		s.partial = s.partial[:0]
+		if _, err := s.w.Write(s.event); err != nil {
+			return written, err
+		}
+
+		p = p[i+1:]
+	}
+}
+
+// StreamSSE streams file data as Server-Sent Events (text/event-stream), an event per line. Browsers consume it with
+// EventSource: each event data is a line without the trailing newline, each event ID is the file offset of the next
+// line. When EventSource reconnects, the stream continues from Last-Event-ID offset (instead of options.InitialOffset),
+// so no lines are lost or repeated. The incomplete last line is sent when the rest of it is written to the file.
+//
+// Content-Type and range options are ignored. Streaming stops when the client disconnects, the file is removed or
+// options.Timeout expires. With options.Trailer the last event is 'end' one, its data is StreamSummary JSON.
+func StreamSSE(w http.ResponseWriter, req *http.Request, filePath string, streamer *Streamer, options HTTPStreamOptions) error {
+	if !streamer.IsRunning() {
+		http.Error(w, "Streaming service is not running", http.StatusServiceUnavailable)
+		return ErrNotRunning
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.Error(w, "Can't open file for streaming: "+err.Error(), http.StatusNotFound)
+		return err
+	}
+	defer file.Close()
+
+	offset := options.InitialOffset
+	if lastID, parseErr := strconv.ParseInt(req.Header.Get("Last-Event-ID"), 10, 64); parseErr == nil && lastID >= 0 {
+		offset = lastID
+	}
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	writeTimeout := options.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = options.Timeout
+	}
+
+	events := &sseWriter{
+		w:      flushWriter{w: w, controller: http.NewResponseController(w), writeTimeout: writeTimeout},
+		offset: offset,
+	}
+	listener := NewListener(file, bufio.NewWriter(events))
+	listener.SetAuditInfo(req.RemoteAddr, "")
+
+	err = streamer.StreamToContext(req.Context(), listener, options.Timeout)
+	if options.Trailer && req.Context().Err() == nil {
+		if writeErr := writeSummaryEvent(events.w, listener); writeErr != nil && err == nil {
+			err = writeErr
+		}
+	}
+
+	if err == req.Context().Err() {
+		return nil // client has gone, that's the regular end of stream
+	}
+
+	return err
+}
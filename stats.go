@@ -0,0 +1,34 @@
+package file_streamer
+
+import "time"
+
+// ListenerStats describes progress of Listener's stream.
+type ListenerStats struct {
+	BytesWritten int64     // file data bytes written to listener's writer (before encoding)
+	Flushes      uint64    // number of writer flushes, i.e. chunks sent to the client
+	LastFlush    time.Time // zero when nothing was flushed yet
+	Offset       int64     // file position of the stream
+}
+
+// Stats returns current stream statistics, for idle detection, progress bars and per-connection reporting.
+func (bs *Listener) Stats() ListenerStats {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	return bs.stats
+}
+
+// recordRead accounts <bytes> of file data streamed up to <offset>.
+func (bs *Listener) recordRead(bytes, offset int64) {
+	bs.mu.Lock()
+	bs.stats.BytesWritten += bytes
+	bs.stats.Offset = offset
+	bs.mu.Unlock()
+}
+
+func (bs *Listener) recordFlush(at time.Time) {
+	bs.mu.Lock()
+	bs.stats.Flushes++
+	bs.stats.LastFlush = at
+	bs.mu.Unlock()
+}
@@ -0,0 +1,45 @@
+package file_streamer
+
+import (
+	"bufio"
+	"testing"
+	"time"
+)
+
+func TestListenerStats(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "0123456789")
+
+	file := openTestFile(t, name)
+	if _, err := file.Seek(4, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	out := &syncBuffer{}
+	listener := NewListener(file, bufio.NewWriter(out))
+	if stats := listener.Stats(); stats != (ListenerStats{}) {
+		t.Errorf("new listener has stats %+v", stats)
+	}
+
+	started := time.Now()
+	result := make(chan error, 1)
+	go func() { result <- s.StreamTo(listener, 0) }()
+	waitSubscribed(t, s, listener)
+	waitForOutput(t, out, "456789")
+
+	appendToFile(t, name, "abc")
+	waitForOutput(t, out, "456789abc")
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	stats := listener.Stats()
+	if stats.BytesWritten != 9 || stats.Offset != 13 || stats.Flushes != 2 {
+		t.Errorf("unexpected stats %+v", stats)
+	}
+	if stats.LastFlush.Before(started) {
+		t.Errorf("last flush time %s is before stream start %s", stats.LastFlush, started)
+	}
+}
@@ -4,9 +4,15 @@ import (
 	"bufio"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -33,7 +39,43 @@ func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
 //
 // To stream file data inside a valid HTTP response without breaking a connection structure, send headers and any other
 // metadata you want to a client before calling StreamRawData().
+//
+// Streaming stops as soon as the client closes its connection. Each write to the connection must finish within
+// <timeout> (when it is not 0), so a dead TCP peer does not block the stream forever. Use StreamRawDataWithDeadline() to
+// set write timeout separately.
 func StreamRawData(filePath string, initialOffset int64, streamer *Streamer, w http.ResponseWriter, timeout time.Duration) error {
+	return StreamRawDataWithDeadline(filePath, initialOffset, streamer, w, timeout, timeout)
+}
+
+// deadlineWriter sets write deadline of connection before each write.
+type deadlineWriter struct {
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (d deadlineWriter) Write(p []byte) (int, error) {
+	if err := d.conn.SetWriteDeadline(time.Now().Add(d.timeout)); err != nil {
+		return 0, err
+	}
+
+	return d.conn.Write(p)
+}
+
+// errClientDisconnected closes listener of the client that has closed its connection.
+var errClientDisconnected = errors.New("client disconnected")
+
+// closeOnDisconnect reads (and drops) everything client sends after the request, closing <listener> as soon as the
+// client closes the connection. Streamer does not wait for the next write to fail or for the inactivity timeout then.
+//
+// Keep in mind a client that half-closes its side of connection after sending request is considered disconnected.
+func closeOnDisconnect(r io.Reader, listener *Listener) {
+	_, _ = io.Copy(ioutil.Discard, r)
+	listener.closeWithError(errClientDisconnected)
+}
+
+// StreamRawDataWithDeadline is StreamRawData() with write deadline: each write to hijacked connection fails after
+// <writeTimeout>, StreamRawDataWithDeadline() returns the timeout error then. 0 means 'no write deadline'.
+func StreamRawDataWithDeadline(filePath string, initialOffset int64, streamer *Streamer, w http.ResponseWriter, timeout, writeTimeout time.Duration) error {
 	if !streamer.IsRunning() {
 		http.Error(w, "Streaming service is not running", http.StatusServiceUnavailable)
 		return ErrNotRunning
@@ -59,13 +101,24 @@ func StreamRawData(filePath string, initialOffset int64, streamer *Streamer, w h
 	}
 	defer conn.Close()
 
+	if writeTimeout != 0 {
+		// hijacked buffer is empty, nothing is lost by Reset()
+		connBuffer.Writer.Reset(deadlineWriter{conn: conn, timeout: writeTimeout})
+	}
+
 	listener := NewListener(file, connBuffer.Writer)
+	listener.SetAuditInfo(conn.RemoteAddr().String(), "")
+	go closeOnDisconnect(connBuffer.Reader, listener)
+
 	err = streamer.StreamTo(listener, timeout)
 
 	switch err {
 	case nil:
 		// no error -> do nothing
 
+	case errClientDisconnected:
+		return nil // regular end of stream, there is nobody to report anything to
+
 	case ErrNotRunning:
 		fmt.Fprintln(connBuffer, "--------------------------------")
 		fmt.Fprintln(connBuffer, "Streaming service is not running")
@@ -79,3 +132,186 @@ func StreamRawData(filePath string, initialOffset int64, streamer *Streamer, w h
 
 	return err
 }
+
+// HTTPStreamOptions configures StreamHTTP().
+type HTTPStreamOptions struct {
+	InitialOffset int64
+	Timeout       time.Duration // see Streamer.StreamTo()
+
+	// WriteTimeout limits each write to the client, so a dead TCP peer does not block the stream forever. Timeout is
+	// used when it is 0 (no write deadline when both are 0).
+	WriteTimeout time.Duration
+
+	// ContentType is sent as Content-Type header as is. When empty, the type is detected from the first 512 bytes of
+	// file (see http.DetectContentType).
+	ContentType string
+
+	// ForceTextPlain sends 'text/plain; charset=utf-8' Content-Type instead of detected one: logs containing HTML
+	// or binary-looking bytes are still shown as text by browsers.
+	ForceTextPlain bool
+
+	// Attachment makes browsers to download the file instead of showing it (Content-Disposition: attachment).
+	Attachment bool
+
+	// Trailer makes StreamHTTP() send StreamSummary JSON in X-Stream-Summary HTTP trailer when the stream ends, and
+	// StreamSSE() send it as the final 'end' event.
+	Trailer bool
+}
+
+// flushWriter flushes each write to HTTP client, so a chunk of chunked response is sent for each portion of file data.
+type flushWriter struct {
+	w            http.ResponseWriter
+	controller   *http.ResponseController
+	writeTimeout time.Duration
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	if f.writeTimeout != 0 {
+		// not all ResponseWriters support deadlines, stream without them then
+		_ = f.controller.SetWriteDeadline(time.Now().Add(f.writeTimeout))
+	}
+
+	n, err := f.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+
+	if err = f.controller.Flush(); err == http.ErrNotSupported {
+		err = nil
+	}
+
+	return n, err
+}
+
+// detectContentType sniffs MIME type of data at the beginning of <file> without moving file position.
+func detectContentType(file *os.File) string {
+	head := make([]byte, 512)
+	n, _ := file.ReadAt(head, 0)
+
+	return http.DetectContentType(head[:n])
+}
+
+// ErrRangeNotSatisfiable is returned by StreamHTTP() when requested range starts beyond the end of file.
+var ErrRangeNotSatisfiable = errors.New("requested range is beyond the end of file")
+
+// parseOpenRange parses 'bytes=N-' Range header value. Other forms of ranges (closed, suffix and multiple ones) are
+// not supported: the header is ignored then, as RFC7233 allows.
+func parseOpenRange(value string) (start int64, isRange bool) {
+	const prefix = "bytes="
+	if !strings.HasPrefix(value, prefix) || !strings.HasSuffix(value, "-") {
+		return 0, false
+	}
+
+	start, err := strconv.ParseInt(strings.TrimSpace(value[len(prefix):len(value)-1]), 10, 64)
+	if err != nil || start < 0 {
+		return 0, false
+	}
+
+	return start, true
+}
+
+// StreamHTTP streams file data inside a valid HTTP response with chunked transfer encoding, keeping the response open
+// while the file is changing. Unlike StreamRawData() it sends headers: Content-Type (detected from data or configured
+// in <options>), Content-Disposition with the file name and X-Content-Type-Options: nosniff.
+//
+// 'Range: bytes=N-' requests are honored, so tools like 'curl -C -' can resume interrupted downloads: data from offset
+// N up to the current end of file is sent with 206 Partial Content status, the file is not followed then. Range
+// starting at or beyond the end of file gets 416 response and ErrRangeNotSatisfiable. Use options.InitialOffset to
+// follow the file from an offset.
+//
+// Streaming stops when the client disconnects (<req> context is done), the file is removed or options.Timeout expires.
+// StreamSummary is sent in X-Stream-Summary trailer then, when options.Trailer is set.
+func StreamHTTP(w http.ResponseWriter, req *http.Request, filePath string, streamer *Streamer, options HTTPStreamOptions) error {
+	if !streamer.IsRunning() {
+		http.Error(w, "Streaming service is not running", http.StatusServiceUnavailable)
+		return ErrNotRunning
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.Error(w, "Can't open file for streaming: "+err.Error(), http.StatusNotFound)
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	header := w.Header()
+	header.Set("Accept-Ranges", "bytes")
+
+	offset := options.InitialOffset
+	rangeStart, isRange := parseOpenRange(req.Header.Get("Range"))
+	if isRange {
+		if rangeStart >= info.Size() {
+			header.Set("Content-Range", fmt.Sprintf("bytes */%d", info.Size()))
+			http.Error(w, "Requested range is beyond the end of file", http.StatusRequestedRangeNotSatisfiable)
+			return ErrRangeNotSatisfiable
+		}
+		offset = rangeStart
+	}
+
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	contentType := options.ContentType
+	switch {
+	case options.ForceTextPlain:
+		contentType = "text/plain; charset=utf-8"
+	case contentType == "":
+		contentType = detectContentType(file)
+	}
+
+	disposition := "inline"
+	if options.Attachment {
+		disposition = "attachment"
+	}
+
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": filepath.Base(filePath)}))
+	header.Set("X-Content-Type-Options", "nosniff")
+
+	if isRange {
+		// Content-Range must name the last byte of the body, so the range ends at the current end of file
+		header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", rangeStart, info.Size()-1, info.Size()))
+		header.Set("Content-Length", strconv.FormatInt(info.Size()-rangeStart, 10))
+		w.WriteHeader(http.StatusPartialContent)
+
+		_, err = io.CopyN(w, file, info.Size()-rangeStart)
+		return err
+	}
+
+	if options.Trailer {
+		header.Set("Trailer", SummaryTrailer)
+	}
+	w.WriteHeader(http.StatusOK)
+
+	writeTimeout := options.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = options.Timeout
+	}
+
+	writer := flushWriter{w: w, controller: http.NewResponseController(w), writeTimeout: writeTimeout}
+	listener := NewListener(file, bufio.NewWriter(writer))
+	listener.SetAuditInfo(req.RemoteAddr, "")
+
+	err = streamer.StreamToContext(req.Context(), listener, options.Timeout)
+	if options.Trailer && req.Context().Err() == nil {
+		if writeTimeout != 0 {
+			// the deadline of the last write may be over already, the trailer is written after the stream
+			_ = writer.controller.SetWriteDeadline(time.Now().Add(writeTimeout))
+		}
+		setSummaryTrailer(w, listener)
+	}
+
+	if err == req.Context().Err() {
+		return nil // client has gone, that's the regular end of stream
+	}
+
+	return err
+}
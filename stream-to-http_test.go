@@ -0,0 +1,217 @@
+package file_streamer
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func streamHTTPServer(t *testing.T, s *Streamer, name string, options HTTPStreamOptions) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		StreamHTTP(w, r, name, s, options)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestStreamHTTPHeaders(t *testing.T) {
+	s := startTestStreamer(t)
+
+	cases := []struct {
+		data        string
+		options     HTTPStreamOptions
+		contentType string
+		disposition string
+	}{
+		{"<html><body>hi</body></html>", HTTPStreamOptions{}, "text/html; charset=utf-8", "inline"},
+		{"<html><body>hi</body></html>", HTTPStreamOptions{ForceTextPlain: true}, "text/plain; charset=utf-8", "inline"},
+		{"\x89PNG\r\n\x1a\n", HTTPStreamOptions{Attachment: true}, "image/png", "attachment"},
+		{"{}", HTTPStreamOptions{ContentType: "application/json"}, "application/json", "inline"},
+	}
+
+	for _, c := range cases {
+		name := createTestFile(t, c.data)
+		c.options.Timeout = 10 * time.Millisecond
+		server := streamHTTPServer(t, s, name, c.options)
+
+		resp, err := http.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(body) != c.data {
+			t.Errorf("%s: body %q, want %q", c.contentType, body, c.data)
+		}
+		if got := resp.Header.Get("Content-Type"); got != c.contentType {
+			t.Errorf("Content-Type %q, want %q", got, c.contentType)
+		}
+		if got, want := resp.Header.Get("Content-Disposition"), c.disposition+"; filename="; !strings.HasPrefix(got, want) {
+			t.Errorf("Content-Disposition %q, want %q...", got, want)
+		}
+		if resp.Header.Get("X-Content-Type-Options") != "nosniff" {
+			t.Error("X-Content-Type-Options header is not set")
+		}
+	}
+}
+
+func TestStreamHTTPNotFound(t *testing.T) {
+	s := startTestStreamer(t)
+	server := streamHTTPServer(t, s, "/non/existent/file", HTTPStreamOptions{})
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+}
+
+func TestStreamHTTPRange(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "0123456789")
+	server := streamHTTPServer(t, s, name, HTTPStreamOptions{Timeout: 10 * time.Millisecond})
+
+	cases := []struct {
+		rangeHeader  string
+		status       int
+		contentRange string
+		body         string
+	}{
+		{"", http.StatusOK, "", "0123456789"},
+		{"bytes=4-", http.StatusPartialContent, "bytes 4-9/10", "456789"},
+		{"bytes=9-", http.StatusPartialContent, "bytes 9-9/10", "9"},
+		{"bytes=10-", http.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+		{"bytes=11-", http.StatusRequestedRangeNotSatisfiable, "bytes */10", ""},
+		{"bytes=2-5", http.StatusOK, "", "0123456789"}, // closed ranges are ignored
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+		if c.rangeHeader != "" {
+			req.Header.Set("Range", c.rangeHeader)
+		}
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != c.status {
+			t.Errorf("%q: status %d, want %d", c.rangeHeader, resp.StatusCode, c.status)
+		}
+		if got := resp.Header.Get("Content-Range"); got != c.contentRange {
+			t.Errorf("%q: Content-Range %q, want %q", c.rangeHeader, got, c.contentRange)
+		}
+		if c.status != http.StatusRequestedRangeNotSatisfiable && string(body) != c.body {
+			t.Errorf("%q: body %q, want %q", c.rangeHeader, body, c.body)
+		}
+	}
+}
+
+// deadConn accepts writes until its deadline passes, like a connection to a dead peer with full TCP buffers.
+type deadConn struct {
+	net.Conn
+	deadline time.Time
+}
+
+func (c *deadConn) SetWriteDeadline(t time.Time) error {
+	c.deadline = t
+	return nil
+}
+
+func (c *deadConn) Write(p []byte) (int, error) {
+	time.Sleep(time.Until(c.deadline))
+	return 0, os.ErrDeadlineExceeded
+}
+
+func TestDeadlineWriter(t *testing.T) {
+	conn := &deadConn{}
+	w := deadlineWriter{conn: conn, timeout: 5 * time.Millisecond}
+
+	started := time.Now()
+	if _, err := w.Write([]byte("data")); err != os.ErrDeadlineExceeded {
+		t.Fatalf("got %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	if conn.deadline.Before(started.Add(5 * time.Millisecond)) {
+		t.Errorf("deadline %s was not derived from write timeout", conn.deadline)
+	}
+}
+
+func TestStreamRawDataWriteTimeout(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, strings.Repeat("x", 32<<20)) // much more than TCP buffers can hold
+
+	result := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result <- StreamRawDataWithDeadline(name, 0, s, w, 0, 20*time.Millisecond)
+	}))
+	defer server.Close()
+
+	// the client sends request and never reads the response: writes block once TCP buffers are full
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.(*net.TCPConn).SetReadBuffer(4096)
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+
+	select {
+	case err = <-result:
+	case <-time.After(5 * time.Second):
+		t.Error("stream is blocked by a client that does not read")
+		conn.Close()
+		err = <-result
+	}
+
+	if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+		t.Errorf("stream ended with %v, want timeout error", err)
+	}
+}
+
+func TestStreamRawDataClientDisconnect(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "data")
+
+	result := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		result <- StreamRawData(name, 0, s, w, 0) // no timeout: only disconnect can stop the stream
+	}))
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n"))
+
+	data := make([]byte, 4)
+	if _, err = io.ReadFull(conn, data); err != nil || string(data) != "data" {
+		t.Fatalf("read %q, %v", data, err)
+	}
+	conn.Close()
+
+	select {
+	case err = <-result:
+		if err != nil {
+			t.Errorf("stream of disconnected client ended with %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not stopped after client disconnected")
+	}
+}
@@ -12,6 +12,7 @@
 package file_streamer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/fsnotify/fsnotify"
@@ -19,6 +20,7 @@ import (
 	"log"
 	"os"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -31,21 +33,72 @@ const (
 
 type empty struct{}
 
-// Maps watched file to the list of channels (readers) to be notified about changes detection.
-type subscriptions map[string]map[newDataChan]empty
+// Maps watched file to the listeners (readers) to be notified about changes detection.
+type subscriptions map[string]map[ListenerID]*Listener
+
+// StreamerService is the streaming API of Streamer. Depend on it instead of *Streamer to be able to replace streaming
+// service with a fake one in tests (see streamertest.MemoryStreamer).
+type StreamerService interface {
+	Start() error
+	Stop() error
+	IsRunning() bool
+	StreamTo(listener *Listener, timeout time.Duration) error
+}
+
+var _ StreamerService = (*Streamer)(nil)
 
 // Streamer is a main package instance that provides streaming service to all Listeners
 type Streamer struct {
 	mu sync.Mutex
 
-	logger *log.Logger
+	logger    *log.Logger
+	auditSink AuditSink
+	tracer    Tracer
+
+	readLimiter  *readLimiter // nil means 'no limit'
+	batchedReads int          // number of buffers filled by a single read syscall, 0 means 'regular reads'
+	memoryBudget uint64       // limit of stream buffers total size, 0 means 'no limit'
+	adaptiveMin  int          // bounds of adaptive read buffers, zero adaptiveMax means 'writer buffer size'
+	adaptiveMax  int
+
+	fileCheckInterval time.Duration // period of streamed files existence checks, 0 means 'on events only'
+
+	networkFSPollInterval time.Duration    // period of network file systems polling, 0 means 'don't poll'
+	networkPaths          map[string]empty // watched paths on network file systems, owned by eventsRouter
+
+	lineIndexEvery int                   // interval of line indexes, 0 means 'no indexing'
+	lineIndexes    map[string]*LineIndex // line indexes of streamed files by their normalized paths
 
-	fsNotify         *fsnotify.Watcher
-	changedFileNames chan string
+	namespaces map[string]*namespace
+
+	watchedPatterns []string             // files streamed since Start() independently of listeners
+	tailLines       int                  // the number of last lines kept for each watched file
+	warm            map[string]*warmFile // streams of watched files by their normalized paths
+	warmDone        chan empty           // closed by Stop() to stop rescans of watched files
+	warmRescans     chan empty           // closed when rescans of watched files are stopped
+	warmStreams     sync.WaitGroup
+
+	pathNormalization PathNormalization
+
+	clock          Clock
+	watcherFactory WatcherFactory
+
+	fsNotify     Watcher
+	changedFiles chan fsnotify.Event
+
+	eventQueue     EventQueueOptions
+	queuedMu       sync.Mutex
+	queuedEvents   map[string]int // the number of queued events of each file, tracked by EventQueueCoalesce only
+	queueSaturated bool           // the queue is above high-water mark, owned by sendChangeEvents
 
 	subscriptions subscriptions
 	subscribe     chan *Listener
 	unsubscribe   chan *Listener
+	stopRequests  chan empty
+	routerCalls   chan func() // functions to be called by eventsRouter, which owns subscriptions
+	routerDone    chan empty
+
+	metrics Metrics // updated atomically
 
 	threads sync.WaitGroup
 
@@ -70,9 +123,21 @@ func New(logger *log.Logger) *Streamer {
 	s := &Streamer{
 		logger: logger,
 
+		tracer:         noopTracer{},
+		clock:          RealClock,
+		watcherFactory: NewFSNotifyWatcher,
+		eventQueue:     EventQueueOptions{}.withDefaults(),
+
+		networkFSPollInterval: defaultNetworkFSPollInterval,
+		networkPaths:          make(map[string]empty),
+		lineIndexes:           make(map[string]*LineIndex),
+		namespaces:            make(map[string]*namespace),
+		warm:                  make(map[string]*warmFile),
+
 		subscriptions: make(subscriptions),
 		subscribe:     make(chan *Listener),
 		unsubscribe:   make(chan *Listener),
+		routerCalls:   make(chan func()),
 
 		state: stateStopped,
 	}
@@ -80,19 +145,19 @@ func New(logger *log.Logger) *Streamer {
 	return s
 }
 
-// read all fs notifications and send changed file names to eventsRouter()
+// read all fs notifications and send them to eventsRouter()
 func (s *Streamer) sendChangeEvents() {
-	defer close(s.changedFileNames)
+	defer close(s.changedFiles)
 	defer s.threads.Done()
 
 	for {
-		fileEvent, isOpen := <-s.fsNotify.Events
+		fileEvent, isOpen := <-s.fsNotify.Events()
 		if !isOpen {
 			// We're not listening file changes any more (watcher is closed and no events left in pool)
 			return
 		}
 
-		s.changedFileNames <- fileEvent.Name
+		s.queueEvent(fileEvent)
 	}
 }
 
@@ -102,7 +167,7 @@ func (s *Streamer) logNotifyErrors() {
 	defer s.threads.Done()
 
 	for {
-		notificationError, isOpen := <-s.fsNotify.Errors
+		notificationError, isOpen := <-s.fsNotify.Errors()
 		if !isOpen {
 			// We're not listening file changes any more (watcher is closed and no errors left in pool)
 			return
@@ -113,76 +178,115 @@ func (s *Streamer) logNotifyErrors() {
 }
 
 // eventsRouter will notify all subscribed channels about changes in particular file.
-// subscribeListener adds listener's 'new data' notification channel to subscriptions list.
+// subscribeListener adds listener to subscriptions list.
 func (s *Streamer) subscribeListener(listener *Listener) {
 	// if it's a first subscription for the given file - prepare subscriptions map and start to listen for file events
-	if _, subscriptionExists := s.subscriptions[listener.file.Name()]; !subscriptionExists {
-		s.subscriptions[listener.file.Name()] = make(map[newDataChan]empty)
+	if _, subscriptionExists := s.subscriptions[listener.watchPath]; !subscriptionExists {
+		s.subscriptions[listener.watchPath] = make(map[ListenerID]*Listener)
+
+		if s.isNetworkListener(listener) {
+			s.logf(listener, "File '%s' is on network file system, polling it every %s", listener.watchPath, s.networkFSPollInterval)
+			s.networkPaths[listener.watchPath] = empty{}
+		}
 
-		err := s.fsNotify.Add(listener.file.Name())
+		err := s.fsNotify.Add(listener.watchPath)
 		if err != nil {
-			s.logger.Printf("Failed to register new fsNotify listener for file '%s': %v", listener.file.Name(), err)
+			s.logf(listener, "Failed to register new fsNotify listener for file '%s': %v", listener.watchPath, err)
+			offset, _ := listener.file.Seek(0, io.SeekCurrent)
+			s.emitEvent(listener, EventWatchLost, offset, err)
 		}
 	}
 
 	// subscribe
-	s.logger.Printf("New listener for '%s' file", listener.file.Name())
-	s.subscriptions[listener.file.Name()][listener.newDataNotifications] = empty{}
+	s.logf(listener, "New listener for '%s' file", listener.file.Name())
+	s.subscriptions[listener.watchPath][listener.id] = listener
 }
 
-// unsubscribeListener removes listener's 'new data' notification channel from subscriptions list.
+// unsubscribeListener removes listener from subscriptions list.
 func (s *Streamer) unsubscribeListener(listener *Listener) {
 	// unsubscribe
-	delete(s.subscriptions[listener.file.Name()], listener.newDataNotifications)
-	s.logger.Printf("File '%s' listener unsubscribed", listener.file.Name())
+	delete(s.subscriptions[listener.watchPath], listener.id)
+	s.logf(listener, "File '%s' listener unsubscribed", listener.file.Name())
 
 	// when it was a last listener for the given file - stop listening and forget about file
-	if len(s.subscriptions[listener.file.Name()]) == 0 {
-		delete(s.subscriptions, listener.file.Name())
+	if len(s.subscriptions[listener.watchPath]) == 0 {
+		delete(s.subscriptions, listener.watchPath)
 
-		err := s.fsNotify.Remove(listener.file.Name())
+		err := s.fsNotify.Remove(listener.watchPath)
 		if err != nil {
-			s.logger.Printf("Failed stop listening fsNotify events of file '%s': %v", listener.file.Name(), err)
+			s.logger.Printf("Failed stop listening fsNotify events of file '%s': %v", listener.watchPath, err)
 		}
+		delete(s.networkPaths, listener.watchPath)
+	}
+}
+
+// notifySubscribers sends 'new data' notification to all listeners of the given file. Listeners check whether the
+// file still exists on the next read when the file was <removed> (or renamed).
+func (s *Streamer) notifySubscribers(filename string, removed bool) {
+	if _, exists := s.subscriptions[filename]; !exists || len(s.subscriptions[filename]) == 0 {
+		s.logger.Printf("No listeners subscribed for '%s' file events", filename)
+		return
+	}
+
+	for _, toNotify := range s.subscriptions[filename] {
+		if removed {
+			atomic.StoreUint32(&toNotify.fileCheckRequested, 1)
+		}
+		s.notifyListener(toNotify)
+	}
+}
+
+// callRouter calls <call> from eventsRouter goroutine, so it can access subscriptions. Returns false when router is
+// not running and <call> was not called.
+func (s *Streamer) callRouter(call func()) bool {
+	if !s.IsRunning() {
+		return false
+	}
+
+	done := make(chan empty)
+	select {
+	case s.routerCalls <- func() { call(); close(done) }:
+		<-done
+		return true
+	case <-s.routerDone:
+		return false
 	}
 }
 
 // eventsRouter receives filesystem events from fsNotify and sends 'new data' notifications to all subscribers.
+//
+// When Stop() is requested, it keeps serving existing subscriptions until all of them are finished, and only then
+// closes fsNotify watcher. fsNotify can't finish Remove() call (and blocks forever) when watcher is closed in the
+// middle of it, so watcher must never be closed concurrently with unsubscribeListener().
 func (s *Streamer) eventsRouter() {
 	defer s.threads.Done()
+	defer close(s.routerDone)
 
-routeEvents:
+	stopRequests := s.stopRequests
+	stopRequested, watcherClosed := false, false
 	for {
 		select {
+		case <-stopRequests:
+			stopRequests = nil // closed channel is always ready, stop listening it
+			stopRequested = true
 		case listener := <-s.subscribe:
 			s.subscribeListener(listener)
 		case listener := <-s.unsubscribe:
 			s.unsubscribeListener(listener)
-		case filename, isOpen := <-s.changedFileNames:
+		case call := <-s.routerCalls:
+			call()
+		case event, isOpen := <-s.changedFiles:
 			if !isOpen {
-				break routeEvents
+				return
 			}
 
-			if _, exists := s.subscriptions[filename]; !exists || len(s.subscriptions[filename]) == 0 {
-				s.logger.Printf("No listeners subscribed for '%s' file events", filename)
-				continue
-			}
-
-			for toNotify := range s.subscriptions[filename] {
-				if len(toNotify) < cap(toNotify) {
-					toNotify <- newDataEvent{}
-				}
-			}
+			s.dequeueEvent(event)
+			s.notifySubscribers(event.Name, event.Op&(fsnotify.Remove|fsnotify.Rename) != 0)
 		}
-	}
 
-	// Wait for all subscriptions to be finished
-	for len(s.subscriptions) != 0 {
-		select {
-		case listener := <-s.subscribe:
-			s.subscribeListener(listener)
-		case listener := <-s.unsubscribe:
-			s.unsubscribeListener(listener)
+		if stopRequested && !watcherClosed && len(s.subscriptions) == 0 {
+			s.fsNotify.Close() // trigger stop chain: fsNotify -> (sendChangeEvents,logNotifyErrors) -> eventsRouter
+			watcherClosed = true
 		}
 	}
 }
@@ -192,15 +296,156 @@ func (s *Streamer) SetLogger(l *log.Logger) {
 	s.logger = l
 }
 
+// SetClock replaces the source of time used for stream timeouts and audit events. Mostly useful for tests.
+//
+// Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetClock(clock Clock) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.clock = clock
+	return nil
+}
+
+// SetWatcherFactory replaces the source of file change events. Streamer calls <factory> on each Start() to get a new
+// Watcher. Mostly useful for tests.
+//
+// The watcher receives all files, network file systems polling is disabled (see SetNetworkFSPollInterval()).
+//
+// Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetWatcherFactory(factory WatcherFactory) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.watcherFactory = factory
+	s.networkFSPollInterval = 0
+	return nil
+}
+
+// SetReadConcurrency limits the number of files read simultaneously by all streams of Streamer. When lots of
+// listeners get new data at the same time, they wait for their turn instead of hammering the disk. Turns are passed
+// across files in round-robin order, so one hot file can't starve the others. Streams of higher priority (see
+// Listener.SetPriority()) get their turns first.
+//
+// Zero <limit> (the default) disables the limit. Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetReadConcurrency(limit int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	if limit <= 0 {
+		s.readLimiter = nil
+	} else {
+		s.readLimiter = newReadLimiter(limit)
+	}
+
+	return nil
+}
+
+// SetBatchedReads makes Streamer to read new file data into <buffers> buffers (each of Listener's writer size) with a
+// single readv(2) syscall, instead of reading buffer by buffer until EOF. It reduces syscalls overhead when hundreds of
+// files are updated each second. Takes effect for streams without encoders and transformers only.
+//
+// Zero <buffers> (the default) disables batched reads. Available on Linux only, returns ErrBatchedReadsUnsupported on
+// other platforms. Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetBatchedReads(buffers int) error {
+	if buffers > 0 && !batchedReadsSupported {
+		return ErrBatchedReadsUnsupported
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	if buffers < 0 {
+		buffers = 0
+	}
+	s.batchedReads = buffers
+
+	return nil
+}
+
+// SetTracer makes Streamer to trace each stream with <tracer> (see Tracer). nil disables tracing.
+func (s *Streamer) SetTracer(tracer Tracer) {
+	if tracer == nil {
+		tracer = noopTracer{}
+	}
+
+	s.mu.Lock()
+	s.tracer = tracer
+	s.mu.Unlock()
+}
+
+// SetAuditSink makes Streamer to report start and finish of each stream to <sink>. nil disables audit.
+func (s *Streamer) SetAuditSink(sink AuditSink) {
+	s.mu.Lock()
+	s.auditSink = sink
+	s.mu.Unlock()
+}
+
+// audit sends stream event to audit sink, if any.
+func (s *Streamer) audit(listener *Listener, action string, offset, bytes int64, started time.Time, err error) {
+	s.mu.Lock()
+	sink := s.auditSink
+	s.mu.Unlock()
+
+	if sink == nil {
+		return
+	}
+
+	listener.mu.Lock()
+	event := AuditEvent{
+		Time:       s.clock.Now(),
+		Action:     action,
+		File:       listener.file.Name(),
+		RemoteAddr: listener.remoteAddr,
+		Principal:  listener.principal,
+		Offset:     offset,
+		Bytes:      bytes,
+		Labels:     listener.labels.clone(),
+	}
+	listener.mu.Unlock()
+
+	if action == AuditStreamFinished {
+		event.Duration = event.Time.Sub(started)
+	}
+	if err != nil {
+		event.Error = err.Error()
+	}
+
+	sink.Audit(event)
+}
+
 // initialize Streamer instance before each .Start()
 func (s *Streamer) init() error {
-	watcher, err := fsnotify.NewWatcher()
+	watcher, err := s.watcherFactory()
 	if err != nil {
 		return err
 	}
+	if s.networkFSPollInterval > 0 {
+		watcher = newStandbyWatcher(watcher, s.networkFSPollInterval, s.isNetworkPath)
+	}
 	s.fsNotify = watcher // we closed it during Stop() process
 
-	s.changedFileNames = make(chan string, 1000) // we closed it during Stop() process
+	s.changedFiles = make(chan fsnotify.Event, s.eventQueue.Capacity) // we closed it during Stop() process
+	s.queuedEvents = make(map[string]int)
+	s.queueSaturated = false
+	s.stopRequests = make(chan empty) // closed by Stop()
+	s.routerDone = make(chan empty)   // closed by eventsRouter on exit
 
 	return nil
 }
@@ -238,6 +483,8 @@ func (s *Streamer) Start() error {
 	s.state = stateRunning
 	s.mu.Unlock()
 
+	s.startWarmFiles()
+
 	return nil
 }
 
@@ -255,7 +502,8 @@ func (s *Streamer) Stop() error {
 	s.state = stateStopping
 	s.mu.Unlock()
 
-	s.fsNotify.Close() // trigger stop chain: fsNotify -> (sendChangeEvents,logNotifyErrors) -> eventsRouter
+	s.stopWarmFiles()
+	close(s.stopRequests) // eventsRouter closes fsNotify when all subscriptions are finished
 	s.threads.Wait()
 
 	s.mu.Lock()
@@ -286,52 +534,398 @@ func (s *Streamer) IsRunning() bool {
 //
 // returns ErrNotRunning when Streamer is not ready for streaming data (was not Start()'ed, or was Stop()'ed)
 //
-// returns ErrListenerClosed when listener is not ready for accepting data.
+// returns ErrListenerClosed when listener is not ready for accepting data: it has already streamed data and was closed.
+//
+// returns ErrListenerInUse when listener is streaming data in another StreamTo() call right now.
 //
+// returns ErrNamespaceLimit when the file belongs to a namespace that has Namespace.MaxStreams streams already.
 func (s *Streamer) StreamTo(listener *Listener, timeout time.Duration) error {
+	return s.StreamToContext(context.Background(), listener, timeout)
+}
+
+// Reasons of stream end, reported in 'stop_reason' attribute of SpanStream span and in StreamSummary
+const (
+	StopReasonClosed      = "closed"
+	StopReasonTimeout     = "timeout"
+	StopReasonFileRemoved = "file_removed"
+	StopReasonContext     = "context_done"
+	StopReasonError       = "error"
+)
+
+// StreamToContext is StreamTo() that also stops streaming when <ctx> is done, returning ctx.Err() then.
+//
+// When Streamer has a Tracer (see SetTracer), stream spans are children of the span in <ctx>, so a tail request can be
+// correlated with the originating HTTP request trace.
+func (s *Streamer) StreamToContext(ctx context.Context, listener *Listener, timeout time.Duration) (err error) {
 	if !s.IsRunning() {
 		return ErrNotRunning
 	}
 
+	if err = listener.startStreaming(); err != nil {
+		return err
+	}
+	defer listener.finishStreaming()
+
+	s.mu.Lock()
+	tracer := s.tracer
+	s.mu.Unlock()
+
+	ctx, span := tracer.Start(ctx, SpanStream)
+	span.SetAttribute("file", listener.file.Name())
+	for key, value := range listener.Labels() {
+		span.SetAttribute("label."+key, value)
+	}
+	stopReason := StopReasonClosed
+	streamStart, startBytes := s.clock.Now(), listener.Stats().BytesWritten
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+		}
+		span.SetAttribute("stop_reason", stopReason)
+		span.End()
+
+		listener.setSummary(stopReason, listener.Stats().BytesWritten-startBytes, s.clock.Now().Sub(streamStart), err)
+	}()
+
+	defer func() {
+		offset, _ := listener.file.Seek(0, io.SeekCurrent)
+		s.emitEvent(listener, EventStreamStopped, offset, err)
+		listener.closeEvents()
+	}()
+
+	if ns := s.namespaceOf(listener.file.Name()); ns != nil {
+		if !ns.acquire() {
+			stopReason = StopReasonError
+			s.logf(listener, "File '%s' stream rejected: namespace '%s' has %d streams already", listener.file.Name(), ns.Name, ns.MaxStreams)
+			return ErrNamespaceLimit
+		}
+		defer func() { ns.release(uint64(listener.Stats().BytesWritten)) }()
+
+		listener.addNamespaceLabels(ns)
+		span.SetAttribute("namespace", ns.Name)
+	}
+
+	listenerBufSize := listener.writeDataTo.Available() + listener.writeDataTo.Buffered()
+
+	listener.mu.Lock()
+	encoder := listener.encoder
+	transformer := listener.newTransformer()
+	skipHoles := listener.skipHoles
+	flushPolicy := listener.flushPolicy
+	heartbeat := listener.heartbeat
+	retryPolicy := listener.retryPolicy
+	priority := listener.priority
+	listener.mu.Unlock()
+
+	batched := s.batchedReads > 0 && encoder == nil && transformer == nil && !skipHoles
+	adaptive := s.adaptiveMax > 0 && encoder == nil && !batched
+	memory := uint64(listenerBufSize)
+	switch {
+	case batched:
+		memory += uint64(s.batchedReads * listenerBufSize)
+	case adaptive:
+		memory = uint64(s.adaptiveMax)
+	}
+
+	if !s.reserveMemory(memory) {
+		stopReason = StopReasonError
+		s.logf(listener, "File '%s' stream rejected: %d bytes of buffers exceed memory budget", listener.file.Name(), memory)
+		return ErrMemoryBudgetExceeded
+	}
+	atomic.StoreUint64(&listener.memory, memory)
+	defer func() {
+		atomic.StoreUint64(&listener.memory, 0)
+		s.releaseMemory(memory)
+	}()
+
+	listener.watchPath = s.normalizePath(listener.file.Name())
+	lineIndex := s.lineIndex(listener)
 	s.subscribe <- listener
 	defer func() { s.unsubscribe <- listener }()
 
-	listenerBufSize := listener.writeDataTo.Available() + listener.writeDataTo.Buffered()
-	buf := make([]byte, listenerBufSize)
+	listener.mu.Lock()
+	checkpoints, consumer := listener.checkpoints, listener.consumer
+	listener.mu.Unlock()
+
+	if checkpoints != nil {
+		if err = listener.resumeFromCheckpoint(checkpoints, consumer); err != nil {
+			stopReason = StopReasonError
+			s.logf(listener, "File '%s' checkpoint load error: %s", listener.file.Name(), err.Error())
+			return err
+		}
+	}
+
+	started := s.clock.Now()
+	startOffset, _ := listener.file.Seek(0, io.SeekCurrent)
+	listener.recordRead(0, startOffset)
+	s.audit(listener, AuditStreamStarted, startOffset, 0, started, nil)
+	s.emitEvent(listener, EventStreamStarted, startOffset, nil)
+	defer func() {
+		endOffset, _ := listener.file.Seek(0, io.SeekCurrent)
+		s.audit(listener, AuditStreamFinished, startOffset, endOffset-startOffset, started, err)
+	}()
+
+	var (
+		buf         []byte
+		adaptiveBuf *adaptiveBuffer
+		batchBufs   [][]byte
+	)
+	switch {
+	case batched:
+		batchBufs = newBatchBuffers(s.batchedReads, listenerBufSize)
+	case adaptive:
+		adaptiveBuf = newAdaptiveBuffer(s.adaptiveMin, s.adaptiveMax)
+	default:
+		buf = make([]byte, listenerBufSize)
+	}
+
+	// flush sends buffered data to the client and checkpoints the position of data sent
+	flush := func() error {
+		buffered := listener.writeDataTo.Buffered()
+		if err := listener.writeDataTo.Flush(); err != nil {
+			if !isClosedPipe(err) {
+				s.logf(listener, "File '%s' stream error: %s", listener.file.Name(), err.Error())
+			}
+			return err
+		}
+		if buffered > 0 {
+			listener.recordFlush(s.clock.Now())
+		}
+
+		if checkpoints != nil {
+			// bytes held by transformer (an incomplete sequence) are not sent yet, they are read again on resume
+			offset, _ := listener.file.Seek(0, io.SeekCurrent)
+			if transformer != nil {
+				offset -= int64(len(transformer.pending))
+			}
+			if err := checkpoints.Save(listener.watchPath, consumer, offset); err != nil {
+				s.logf(listener, "File '%s' checkpoint save error: %s", listener.file.Name(), err.Error())
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	defer func() {
+		if err != nil && isClosedPipe(err) {
+			// the reader has gone (e.g. the process consuming the stream exited): a regular end of stream
+			s.logf(listener, "File '%s' reader closed the pipe, stream stopped", listener.file.Name())
+			err, stopReason = nil, StopReasonClosed
+		}
+	}()
+
+	flushTimer := getTimer(s.clock, 0)
+	flushPending := false // data held by flush policy is waiting for flushTimer
+	defer func() {
+		flushTimer.Stop()
+		if flushPending && err == nil {
+			if err = flush(); err != nil {
+				stopReason = StopReasonError
+			}
+		}
+	}()
+
+	heartbeatTimer := getTimer(s.clock, heartbeat)
+	defer heartbeatTimer.Stop()
+
+	fileState := newFileState(listener.file)
+	fileCheckTimer := getTimer(s.clock, s.fileCheckInterval)
+	defer fileCheckTimer.Stop()
+
+	retryTimer := getTimer(s.clock, 0)
+	defer retryTimer.Stop()
+	retries := 0          // failed reads in a row
+	retryPending := false // the failed read waits for retryTimer, file changes don't bring the retry closer
+
+	timeoutTimer := getTimer(s.clock, timeout)
+	for spanName := SpanCatchUp; ; spanName = SpanFlush {
+		lastRead := false
 
-	timeoutTimer := getTimer(timeout)
-	for {
 		select {
-		case _, isOpen := <-listener.newDataNotifications:
-			if !isOpen {
+		case <-listener.newDataNotifications:
+		case <-listener.closed:
+			if closeErr := listener.closeError(); closeErr != nil {
+				stopReason = StopReasonError
+				return closeErr
+			}
+
+			// Notifications received before Close() are still served, just like a closed buffered channel would do.
+			select {
+			case <-listener.newDataNotifications:
+				lastRead = true
+			default:
+				return nil
+			}
+		case <-timeoutTimer.C():
+			// Just stop streaming after <timeout> of inactivity (no changes in file)
+			stopReason = StopReasonTimeout
+			return nil
+		case <-ctx.Done():
+			stopReason = StopReasonContext
+			return ctx.Err()
+		case <-flushTimer.C():
+			flushPending = false
+			if err = flush(); err != nil {
+				stopReason = StopReasonError
+				return err
+			}
+			continue
+		case <-heartbeatTimer.C():
+			heartbeatTimer.Reset(heartbeat)
+			offset, _ := listener.file.Seek(0, io.SeekCurrent)
+			s.emitEvent(listener, EventHeartbeat, offset, nil)
+			continue
+		case <-retryTimer.C():
+			// retry the failed read
+			retryPending = false
+		case <-fileCheckTimer.C():
+			// removal of a file that is still open produces no events, check it is still there from time to time
+			fileCheckTimer.Reset(s.fileCheckInterval)
+			offset, _ := listener.file.Seek(0, io.SeekCurrent)
+			if !s.checkFileState(listener, fileState, offset) {
+				stopReason = StopReasonFileRemoved
 				return nil
 			}
+			continue
+		}
 
-			listener.file.Seek(0, 1) // re-set current position to be able to read to EOF again
+		if retryPending {
+			// the retry reads the new data as well
+			if lastRead {
+				return nil
+			}
+			continue
+		}
 
-			_, err := io.CopyBuffer(listener.writeDataTo, listener.file, buf)
+		if listener.IsPaused() {
+			// new data accumulates in file, the position is kept until Resume()
+			if lastRead {
+				return nil
+			}
+			if timeout != 0 {
+				timeoutTimer.Reset(timeout)
+			}
+			continue
+		}
 
-			if err != nil {
-				fmt.Fprintf(listener.writeDataTo, "Could not stream file data: %s", err.Error())
-				_ = listener.writeDataTo.Flush()
+		if seek, requested := listener.takeSeekRequest(); requested {
+			if _, seekErr := listener.file.Seek(seek.offset, seek.whence); seekErr != nil {
+				s.logf(listener, "File '%s' seek error: %s", listener.file.Name(), seekErr.Error())
+			} else if transformer != nil {
+				transformer.reset() // incomplete sequence left before seek does not continue at the new position
+			}
+		}
 
-				s.logger.Printf("File '%s' stream error: %s", listener.file.Name(), err.Error())
-				return err
+		// re-set current position to be able to read to EOF again
+		readOffset, _ := listener.file.Seek(0, io.SeekCurrent)
+
+		_, readSpan := tracer.Start(ctx, spanName)
+		readSpan.SetAttribute("offset", readOffset)
+
+		copyData := func(src io.Reader) (err error) {
+			switch {
+			case adaptiveBuf != nil && transformer == nil:
+				err = adaptiveBuf.copy(listener.writeDataTo, src)
+			case adaptiveBuf != nil:
+				err = copyChunks(listener.writeDataTo, src, adaptiveBuf.buf, transformer, nil)
+			case encoder == nil && transformer == nil:
+				_, err = io.CopyBuffer(listener.writeDataTo, src, buf)
+			default:
+				err = copyChunks(listener.writeDataTo, src, buf, transformer, encoder)
 			}
+			return err
+		}
+
+		s.readLimiter.acquire(listener.watchPath, priority)
+		if batchBufs != nil {
+			err = copyBatched(listener.writeDataTo, listener.file, batchBufs)
+		} else if skipHoles {
+			err = copyDataRegions(listener.file, copyData)
+		} else {
+			err = copyData(listener.file)
+		}
+		s.readLimiter.release()
+
+		newOffset, _ := listener.file.Seek(0, io.SeekCurrent)
+		readSpan.SetAttribute("bytes", newOffset-readOffset)
+		listener.recordRead(newOffset-readOffset, newOffset)
+		if lineIndex != nil {
+			if indexErr := lineIndex.Update(listener.file, newOffset); indexErr != nil {
+				s.logf(listener, "File '%s' line index error: %s", listener.file.Name(), indexErr.Error())
+			}
+		}
+		if adaptiveBuf != nil {
+			adaptiveBuf.observe(newOffset - readOffset)
+		}
+		if err != nil {
+			readSpan.RecordError(err)
+		}
+		readSpan.End()
+
+		if err != nil && isClosedPipe(err) {
+			return err
+		}
+
+		if err != nil && isPermissionError(err) {
+			s.emitEvent(listener, EventPermissionDenied, newOffset, err)
+			if retries < retryPolicy.MaxAttempts {
+				delay := retryPolicy.backoff(retries)
+				s.logf(listener, "File '%s' read error, retrying in %s: %s", listener.file.Name(), delay, err.Error())
+				retryTimer.Reset(delay)
+				retryPending = true
+				retries++
+				err = nil
+				continue
+			}
+		}
+		if err == nil {
+			retries = 0
+		}
 
-			// Force all data to be sent to client
-			err = listener.writeDataTo.Flush()
-			if err != nil {
-				s.logger.Printf("File '%s' stream error: %s", listener.file.Name(), err.Error())
+		if err != nil {
+			stopReason = StopReasonError
+			if listener.hasEventsConsumer() {
+				s.emitEvent(listener, EventError, newOffset, err)
+			} else {
+				errMessage := fmt.Sprintf("Could not stream file data: %s", err.Error())
+				if encoder != nil {
+					_ = encoder.Encode(listener.writeDataTo, []byte(errMessage))
+				} else {
+					_, _ = listener.writeDataTo.WriteString(errMessage)
+				}
+			}
+			_ = listener.writeDataTo.Flush()
+
+			s.logf(listener, "File '%s' stream error: %s", listener.file.Name(), err.Error())
+			return err
+		}
+
+		// Force all data to be sent to client, unless flush policy holds it for a while
+		if flushPolicy.due(listener.writeDataTo.Buffered()) {
+			if err = flush(); err != nil {
+				stopReason = StopReasonError
 				return err
 			}
+			if flushPending {
+				flushTimer.Stop()
+				flushPending = false
+			}
+		} else if !flushPending {
+			flushTimer.Reset(flushPolicy.MaxDelay)
+			flushPending = true
+		}
 
-			// Is file exist? If not - just stop streaming
-			if _, err = os.Stat(listener.file.Name()); err != nil {
+		// Is file exist? If not - just stop streaming. The file is stat'ed only when there are signs of changes:
+		// removal (or rename) event, or no new data after notification (which happens on truncation).
+		if newOffset == readOffset || atomic.SwapUint32(&listener.fileCheckRequested, 0) == 1 {
+			if !s.checkFileState(listener, fileState, newOffset) {
+				stopReason = StopReasonFileRemoved
 				return nil
 			}
-		case <-timeoutTimer.C:
-			// Just stop streaming after <timeout> of inactivity (no changes in file)
+		}
+
+		if lastRead {
 			return nil
 		}
 
@@ -0,0 +1,253 @@
+package file_streamer
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func startTestStreamer(t *testing.T) *Streamer {
+	s := New(log.New(ioutil.Discard, "", 0))
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Stop() })
+
+	return s
+}
+
+func createTestFile(t testing.TB, data string) string {
+	f, err := ioutil.TempFile("", "file-streamer-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	if _, err = f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+
+	name := f.Name()
+	t.Cleanup(func() { os.Remove(name) })
+
+	return name
+}
+
+func openTestFile(t testing.TB, name string) *os.File {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+
+	return f
+}
+
+// catFile streams file contents once: closed listener makes Streamer to read the file exactly once.
+func catFile(t *testing.T, s *Streamer, listener *Listener) {
+	listener.Close()
+	if err := s.StreamTo(listener, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStreamToAudit(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "0123456789")
+
+	var events []AuditEvent
+	s.SetAuditSink(AuditSinkFunc(func(event AuditEvent) { events = append(events, event) }))
+
+	file := openTestFile(t, name)
+	file.Seek(4, 0)
+
+	var out bytes.Buffer
+	listener := NewListener(file, bufio.NewWriter(&out))
+	listener.SetAuditInfo("127.0.0.1:1234", "alice")
+	catFile(t, s, listener)
+
+	if len(events) != 2 {
+		t.Fatalf("got %d audit events, want 2", len(events))
+	}
+
+	started, finished := events[0], events[1]
+	if started.Action != AuditStreamStarted || finished.Action != AuditStreamFinished {
+		t.Errorf("unexpected actions %q, %q", started.Action, finished.Action)
+	}
+
+	for _, event := range events {
+		if event.File != name || event.RemoteAddr != "127.0.0.1:1234" || event.Principal != "alice" || event.Offset != 4 {
+			t.Errorf("unexpected event %+v", event)
+		}
+	}
+
+	if finished.Bytes != 6 {
+		t.Errorf("finished event reports %d bytes, want 6", finished.Bytes)
+	}
+}
+
+// Offsets beyond 4GB must survive all the way to audit events: the file is sparse, so it does not take disk space.
+func TestStreamToBeyond4GB(t *testing.T) {
+	const offset = 5 << 30
+
+	s := startTestStreamer(t)
+	name := createTestFile(t, "")
+	if err := os.Truncate(name, offset); err != nil {
+		t.Skipf("can't create sparse file: %v", err)
+	}
+
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("tail")
+	f.Close()
+
+	var events []AuditEvent
+	s.SetAuditSink(AuditSinkFunc(func(event AuditEvent) { events = append(events, event) }))
+
+	file := openTestFile(t, name)
+	if _, err = file.Seek(offset, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	catFile(t, s, NewListener(file, bufio.NewWriter(&out)))
+
+	if out.String() != "tail" {
+		t.Errorf("streamed %q, want %q", out.String(), "tail")
+	}
+
+	finished := events[len(events)-1]
+	if finished.Offset != offset || finished.Bytes != 4 {
+		t.Errorf("audit offset %d and bytes %d, want %d and 4", finished.Offset, finished.Bytes, int64(offset))
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe for reading while Streamer writes into it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// waitForOutput waits until <out> contains exactly <want>.
+func waitForOutput(t *testing.T, out *syncBuffer, want string) {
+	for i := 0; i < 2000; i++ {
+		if out.String() == want {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("got %q, want %q", out.String(), want)
+}
+
+func TestPauseResume(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "before\n")
+
+	out := &syncBuffer{}
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(out))
+
+	result := make(chan error)
+	go func() { result <- s.StreamTo(listener, 0) }()
+	waitForOutput(t, out, "before\n")
+
+	listener.Pause()
+	appendToFile(t, name, "during\n")
+	time.Sleep(20 * time.Millisecond)
+	if out.String() != "before\n" {
+		t.Fatalf("paused stream got %q", out.String())
+	}
+
+	listener.Resume()
+	waitForOutput(t, out, "before\nduring\n")
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSeekWhileStreaming(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "0123456789")
+
+	out := &syncBuffer{}
+	listener := NewListener(openTestFile(t, name), bufio.NewWriter(out))
+
+	result := make(chan error)
+	go func() { result <- s.StreamTo(listener, 0) }()
+	waitForOutput(t, out, "0123456789")
+
+	if err := listener.SeekTo(2, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	waitForOutput(t, out, "012345678923456789")
+
+	listener.Pause()
+	appendToFile(t, name, "skipped")
+	listener.SeekToEnd()
+	listener.Resume()
+	appendToFile(t, name, "tail")
+	waitForOutput(t, out, "012345678923456789tail")
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSeekToValidation(t *testing.T) {
+	listener := NewListener(nil, nil)
+
+	if err := listener.SeekTo(-1, io.SeekStart); err != ErrInvalidSeek {
+		t.Errorf("negative offset: got %v, want %v", err, ErrInvalidSeek)
+	}
+	if err := listener.SeekTo(0, 42); err != ErrInvalidSeek {
+		t.Errorf("unknown whence: got %v, want %v", err, ErrInvalidSeek)
+	}
+}
+
+func TestListenerSingleUse(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "data")
+
+	listener, result := startTestStream(t, s, name)
+	if err := s.StreamTo(listener, 0); err != ErrListenerInUse {
+		t.Errorf("second stream of listener returned %v, want %v", err, ErrListenerInUse)
+	}
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.StreamTo(listener, 0); err != ErrListenerClosed {
+		t.Errorf("stream of closed listener returned %v, want %v", err, ErrListenerClosed)
+	}
+
+	// closed, but never streamed listener reads the file once
+	cat := NewListener(openTestFile(t, name), bufio.NewWriter(ioutil.Discard))
+	catFile(t, s, cat)
+}
@@ -0,0 +1,116 @@
+// Package streamertest provides fakes for testing code built on top of file_streamer without real file system
+// notifications and sleeps.
+package streamertest
+
+import (
+	"github.com/badoo/file-streamer"
+	"sync"
+	"time"
+)
+
+// FakeClock is a file_streamer.Clock that moves forward only when Advance() is called.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	timers  map[*fakeTimer]struct{} // active (not fired and not stopped) timers
+	created int
+}
+
+// NewFakeClock creates a fake clock set to <now>.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{
+		now:    now,
+		timers: make(map[*fakeTimer]struct{}),
+	}
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+// Now returns current fake time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer creates a timer that fires when clock is advanced by <d> or more.
+func (c *FakeClock) NewTimer(d time.Duration) file_streamer.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{
+		clock: c,
+		c:     make(chan time.Time, 1),
+	}
+	t.resetLocked(d)
+
+	c.created++
+	c.cond.Broadcast()
+
+	return t
+}
+
+// Advance moves the clock forward by <d> and fires all timers that expire during this period.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for t := range c.timers {
+		if !t.deadline.After(c.now) {
+			delete(c.timers, t)
+			select {
+			case t.c <- c.now:
+			default:
+			}
+		}
+	}
+}
+
+// WaitForTimers blocks until at least <n> timers were created by the clock since its creation. Use it to make sure
+// the code under test armed its timer before advancing the clock.
+func (c *FakeClock) WaitForTimers(n int) {
+	c.mu.Lock()
+	for c.created < n {
+		c.cond.Wait()
+	}
+	c.mu.Unlock()
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	_, active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	_, active := t.clock.timers[t]
+	t.resetLocked(d)
+
+	return active
+}
+
+func (t *fakeTimer) resetLocked(d time.Duration) {
+	t.deadline = t.clock.now.Add(d)
+	t.clock.timers[t] = struct{}{}
+}
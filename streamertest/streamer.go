@@ -0,0 +1,240 @@
+package streamertest
+
+import (
+	"github.com/badoo/file-streamer"
+	"io"
+	"sync"
+	"time"
+)
+
+// MemoryStreamer is an in-memory file_streamer.StreamerService implementation.
+//
+// It streams data of in-memory files (see WriteFile and AppendFile) instead of real ones: listener's file is used
+// only for its name and current position. Tests push 'file changed' events explicitly and can inspect everything that
+// was written to each listener.
+type MemoryStreamer struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	clock   file_streamer.Clock
+	running bool
+
+	files   map[string][]byte
+	streams map[*file_streamer.Listener]*memoryStream
+	written map[*file_streamer.Listener][]byte
+}
+
+type memoryStream struct {
+	name    string
+	offset  int64
+	changes chan struct{}
+}
+
+var _ file_streamer.StreamerService = (*MemoryStreamer)(nil)
+
+// NewMemoryStreamer creates in-memory streamer that uses <clock> for stream timeouts. nil <clock> means
+// file_streamer.RealClock.
+func NewMemoryStreamer(clock file_streamer.Clock) *MemoryStreamer {
+	if clock == nil {
+		clock = file_streamer.RealClock
+	}
+
+	m := &MemoryStreamer{
+		clock:   clock,
+		files:   make(map[string][]byte),
+		streams: make(map[*file_streamer.Listener]*memoryStream),
+		written: make(map[*file_streamer.Listener][]byte),
+	}
+	m.cond = sync.NewCond(&m.mu)
+
+	return m
+}
+
+// Start marks streamer as running.
+func (m *MemoryStreamer) Start() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.running {
+		return file_streamer.ErrRunning
+	}
+
+	m.running = true
+	return nil
+}
+
+// Stop marks streamer as not running and waits for all streams to finish, just like file_streamer.Streamer does.
+func (m *MemoryStreamer) Stop() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.running {
+		return file_streamer.ErrNotRunning
+	}
+
+	m.running = false
+	for len(m.streams) != 0 {
+		m.cond.Wait()
+	}
+
+	return nil
+}
+
+// IsRunning reports whether streamer is running.
+func (m *MemoryStreamer) IsRunning() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.running
+}
+
+// WriteFile replaces contents of in-memory file <name> and notifies its listeners about the change.
+// When file becomes shorter than listener's position, the listener continues from the end of new data.
+func (m *MemoryStreamer) WriteFile(name string, data []byte) {
+	m.mu.Lock()
+	m.files[name] = append([]byte(nil), data...)
+	m.mu.Unlock()
+
+	m.Notify(name)
+}
+
+// AppendFile appends <data> to in-memory file <name> and notifies its listeners about the change.
+func (m *MemoryStreamer) AppendFile(name string, data []byte) {
+	m.mu.Lock()
+	m.files[name] = append(m.files[name], data...)
+	m.mu.Unlock()
+
+	m.Notify(name)
+}
+
+// Notify sends synthetic 'file changed' event to all listeners of file <name>.
+func (m *MemoryStreamer) Notify(name string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, stream := range m.streams {
+		if stream.name == name {
+			notify(stream.changes)
+		}
+	}
+}
+
+// Written returns all data written to <listener> so far.
+func (m *MemoryStreamer) Written(listener *file_streamer.Listener) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]byte(nil), m.written[listener]...)
+}
+
+// WaitForListeners blocks until there are at least <n> active streams of file <name>.
+func (m *MemoryStreamer) WaitForListeners(name string, n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for m.countListeners(name) < n {
+		m.cond.Wait()
+	}
+}
+
+func (m *MemoryStreamer) countListeners(name string) int {
+	count := 0
+	for _, stream := range m.streams {
+		if stream.name == name {
+			count++
+		}
+	}
+
+	return count
+}
+
+func notify(changes chan struct{}) {
+	select {
+	case changes <- struct{}{}:
+	default: // listener already has a pending notification
+	}
+}
+
+// StreamTo streams in-memory file data to <listener> the same way file_streamer.Streamer.StreamTo() does: until the
+// listener is closed or file is not changed for <timeout>.
+func (m *MemoryStreamer) StreamTo(listener *file_streamer.Listener, timeout time.Duration) error {
+	offset, err := listener.File().Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+
+	stream := &memoryStream{
+		name:    listener.File().Name(),
+		offset:  offset,
+		changes: make(chan struct{}, 1),
+	}
+	notify(stream.changes) // initial read
+
+	m.mu.Lock()
+	if !m.running {
+		m.mu.Unlock()
+		return file_streamer.ErrNotRunning
+	}
+	m.streams[listener] = stream
+	m.cond.Broadcast()
+	m.mu.Unlock()
+
+	defer func() {
+		m.mu.Lock()
+		delete(m.streams, listener)
+		m.cond.Broadcast()
+		m.mu.Unlock()
+	}()
+
+	timer := m.clock.NewTimer(timeout)
+	if timeout == 0 {
+		timer.Stop()
+	}
+
+	for {
+		lastRead := false
+
+		select {
+		case <-stream.changes:
+		case <-listener.Closed():
+			select {
+			case <-stream.changes:
+				lastRead = true
+			default:
+				return nil
+			}
+		case <-timer.C():
+			return nil
+		}
+
+		if err = m.sendChanges(listener, stream); err != nil {
+			return err
+		}
+
+		if lastRead {
+			return nil
+		}
+
+		if timeout != 0 {
+			timer.Reset(timeout)
+		}
+	}
+}
+
+func (m *MemoryStreamer) sendChanges(listener *file_streamer.Listener, stream *memoryStream) error {
+	m.mu.Lock()
+	data := m.files[stream.name]
+	if stream.offset > int64(len(data)) {
+		stream.offset = int64(len(data))
+	}
+	data = data[stream.offset:]
+	stream.offset += int64(len(data))
+	m.written[listener] = append(m.written[listener], data...)
+	m.mu.Unlock()
+
+	if _, err := listener.Writer().Write(data); err != nil {
+		return err
+	}
+
+	return listener.Writer().Flush()
+}
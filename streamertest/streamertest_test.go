@@ -0,0 +1,293 @@
+package streamertest
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/badoo/file-streamer"
+	"github.com/fsnotify/fsnotify"
+	"golang.org/x/text/transform"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// chanWriter sends each written chunk into a channel.
+type chanWriter chan string
+
+func (w chanWriter) Write(p []byte) (int, error) {
+	w <- string(p)
+	return len(p), nil
+}
+
+func startStreamer(t *testing.T, clock *FakeClock, watcher *FakeWatcher) *file_streamer.Streamer {
+	s := file_streamer.New(log.New(ioutil.Discard, "", 0))
+	if err := s.SetClock(clock); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SetWatcherFactory(watcher.Factory()); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func tempFile(t *testing.T, data string) *os.File {
+	f, err := ioutil.TempFile("", "streamertest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		f.Close()
+		os.Remove(f.Name())
+	})
+
+	if _, err = f.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+func waitWatched(t *testing.T, watcher *FakeWatcher, name string) {
+	for i := 0; !watcher.IsWatched(name); i++ {
+		if i > 1000 {
+			t.Fatalf("%s is not watched", name)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFakeClockTimers(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	timer := clock.NewTimer(time.Second)
+
+	clock.Advance(999 * time.Millisecond)
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired too early")
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	select {
+	case fired := <-timer.C():
+		if !fired.Equal(time.Unix(1, 0)) {
+			t.Errorf("timer fired at %v", fired)
+		}
+	default:
+		t.Fatal("timer did not fire")
+	}
+
+	if timer.Stop() {
+		t.Error("Stop() of fired timer reports it was active")
+	}
+}
+
+func TestStreamWithFakes(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	watcher := NewFakeWatcher()
+	streamer := startStreamer(t, clock, watcher)
+
+	file := tempFile(t, "first\n")
+	readFrom, err := os.Open(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer readFrom.Close()
+
+	received := make(chanWriter, 10)
+	listener := file_streamer.NewListener(readFrom, bufio.NewWriter(received))
+
+	result := make(chan error)
+	go func() { result <- streamer.StreamTo(listener, time.Minute) }()
+
+	if data := <-received; data != "first\n" {
+		t.Errorf("got %q on stream start", data)
+	}
+
+	waitWatched(t, watcher, file.Name())
+	file.WriteString("second\n")
+	watcher.Emit(file.Name(), fsnotify.Write)
+
+	if data := <-received; data != "second\n" {
+		t.Errorf("got %q after file change", data)
+	}
+
+	// no changes for a minute of fake time: stream ends by timeout
+	clock.WaitForTimers(1)
+	clock.Advance(time.Minute)
+
+	select {
+	case err = <-result:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not finish on timeout")
+	}
+
+	if err = streamer.Stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(watcher.Watched()) != 0 {
+		t.Errorf("files are still watched after stream end: %v", watcher.Watched())
+	}
+}
+
+// deniedTransformer fails each chunk with a permission error, like a read of the file with briefly changed mode.
+type deniedTransformer struct {
+	transform.NopResetter
+}
+
+func (deniedTransformer) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	return 0, 0, &os.PathError{Op: "read", Path: "app.log", Err: syscall.EACCES}
+}
+
+// lockedBuffer is bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+func TestPermissionRetries(t *testing.T) {
+	clock := NewFakeClock(time.Unix(0, 0))
+	watcher := NewFakeWatcher()
+	streamer := startStreamer(t, clock, watcher)
+	defer streamer.Stop()
+
+	// each read consumes a chunk of 16 bytes, so there is data for all attempts
+	file := tempFile(t, strings.Repeat("x", 160))
+	readFrom, err := os.Open(file.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer readFrom.Close()
+
+	var out lockedBuffer
+	listener := file_streamer.NewListener(readFrom, bufio.NewWriterSize(&out, 16))
+	defer listener.Close()
+	listener.AddTransformer(deniedTransformer{})
+	listener.SetRetryPolicy(file_streamer.RetryPolicy{MaxAttempts: 2, MinBackoff: time.Second, MaxBackoff: time.Second})
+	events := listener.Events()
+
+	result := make(chan error, 1)
+	go func() { result <- streamer.StreamTo(listener, 0) }()
+
+	// nextEvent returns the next event except the stream start, advancing the clock when <advance> is set
+	nextEvent := func(advance bool) (file_streamer.StreamEvent, bool) {
+		for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); {
+			select {
+			case event := <-events:
+				if event.Type == file_streamer.EventStreamStarted {
+					continue
+				}
+				return event, true
+			case <-time.After(10 * time.Millisecond):
+				if advance {
+					clock.Advance(time.Second)
+				}
+			}
+		}
+		return file_streamer.StreamEvent{}, false
+	}
+
+	if event, ok := nextEvent(false); !ok || event.Type != file_streamer.EventPermissionDenied {
+		t.Fatalf("got %v event on start, want %v", event.Type, file_streamer.EventPermissionDenied)
+	}
+
+	// file changes must not bring the retry closer
+	waitWatched(t, watcher, file.Name())
+	watcher.Emit(file.Name(), fsnotify.Write)
+	select {
+	case event := <-events:
+		t.Fatalf("got %v event before retry delay", event.Type)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for attempt := 1; attempt <= 2; attempt++ {
+		if event, ok := nextEvent(true); !ok || event.Type != file_streamer.EventPermissionDenied {
+			t.Fatalf("got %v event on retry %d, want %v", event.Type, attempt, file_streamer.EventPermissionDenied)
+		}
+	}
+
+	if event, ok := nextEvent(true); !ok || event.Type != file_streamer.EventError {
+		t.Fatalf("got %v event after all attempts, want %v", event.Type, file_streamer.EventError)
+	}
+
+	select {
+	case err = <-result:
+		if !os.IsPermission(err) {
+			t.Errorf("stream failed with %v, want permission error", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream did not fail after all attempts")
+	}
+
+	if out.String() != "" {
+		t.Errorf("client got %q, want no data and no error text", out.String())
+	}
+}
+
+func TestMemoryStreamer(t *testing.T) {
+	streamer := NewMemoryStreamer(nil)
+	if err := streamer.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	file := tempFile(t, "") // provides the name only, data is taken from memory
+	streamer.WriteFile(file.Name(), []byte("hello"))
+
+	received := make(chanWriter, 10)
+	listener := file_streamer.NewListener(file, bufio.NewWriter(received))
+
+	var service file_streamer.StreamerService = streamer
+	result := make(chan error)
+	go func() { result <- service.StreamTo(listener, 0) }()
+
+	if data := <-received; data != "hello" {
+		t.Errorf("got %q on stream start", data)
+	}
+
+	streamer.WaitForListeners(file.Name(), 1)
+	streamer.AppendFile(file.Name(), []byte(", world"))
+
+	if data := <-received; data != ", world" {
+		t.Errorf("got %q after append", data)
+	}
+
+	listener.Close()
+	if err := <-result; err != nil {
+		t.Fatal(err)
+	}
+
+	if written := string(streamer.Written(listener)); written != "hello, world" {
+		t.Errorf("listener got %q", written)
+	}
+
+	if err := streamer.Stop(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,145 @@
+package streamertest
+
+import (
+	"errors"
+	"github.com/badoo/file-streamer"
+	"github.com/fsnotify/fsnotify"
+	"sort"
+	"sync"
+)
+
+// ErrWatcherClosed is returned by FakeWatcher methods after Close() call.
+var ErrWatcherClosed = errors.New("watcher is closed")
+
+// FakeWatcher is a file_streamer.Watcher that reports file changes only when test asks it to do so with Emit().
+type FakeWatcher struct {
+	mu      sync.Mutex
+	watched map[string]int // watched name -> number of Add() calls
+	closed  bool
+
+	events chan fsnotify.Event
+	errors chan error
+}
+
+// NewFakeWatcher creates a new fake watcher.
+func NewFakeWatcher() *FakeWatcher {
+	return &FakeWatcher{
+		watched: make(map[string]int),
+		events:  make(chan fsnotify.Event, 100),
+		errors:  make(chan error, 100),
+	}
+}
+
+// Factory returns file_streamer.WatcherFactory which always returns this watcher. Use it with
+// Streamer.SetWatcherFactory(). Keep in mind the watcher can't be reused after Streamer.Stop().
+func (w *FakeWatcher) Factory() file_streamer.WatcherFactory {
+	return func() (file_streamer.Watcher, error) {
+		return w, nil
+	}
+}
+
+// Add starts 'watching' the file.
+func (w *FakeWatcher) Add(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWatcherClosed
+	}
+
+	w.watched[name]++
+	return nil
+}
+
+// Remove stops 'watching' the file.
+func (w *FakeWatcher) Remove(name string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWatcherClosed
+	}
+
+	if _, isWatched := w.watched[name]; !isWatched {
+		return errors.New("can't remove non-existent watch for: " + name)
+	}
+
+	delete(w.watched, name)
+	return nil
+}
+
+// Close closes Events() and Errors() channels.
+func (w *FakeWatcher) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+	close(w.events)
+	close(w.errors)
+
+	return nil
+}
+
+// Events returns the channel of emitted events.
+func (w *FakeWatcher) Events() <-chan fsnotify.Event {
+	return w.events
+}
+
+// Errors returns the channel of emitted errors.
+func (w *FakeWatcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Emit sends file change event <op> for file <name>, whether it is watched or not. Returns ErrWatcherClosed when
+// watcher was closed.
+func (w *FakeWatcher) Emit(name string, op fsnotify.Op) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWatcherClosed
+	}
+
+	w.events <- fsnotify.Event{Name: name, Op: op}
+	return nil
+}
+
+// EmitError sends watcher error. Returns ErrWatcherClosed when watcher was closed.
+func (w *FakeWatcher) EmitError(err error) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWatcherClosed
+	}
+
+	w.errors <- err
+	return nil
+}
+
+// Watched returns sorted list of currently watched names.
+func (w *FakeWatcher) Watched() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	names := make([]string, 0, len(w.watched))
+	for name := range w.watched {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// IsWatched reports whether <name> is currently watched.
+func (w *FakeWatcher) IsWatched(name string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, isWatched := w.watched[name]
+	return isWatched
+}
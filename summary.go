@@ -0,0 +1,97 @@
+package file_streamer
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SummaryTrailer is the HTTP trailer with JSON of StreamSummary, sent by StreamHTTP() when HTTPStreamOptions.Trailer
+// is set.
+const SummaryTrailer = "X-Stream-Summary"
+
+// maxCloseReason is the limit of WebSocket close message reason length: control frames carry 125 bytes at most,
+// 2 of them are taken by the close code.
+const maxCloseReason = 123
+
+// StreamSummary describes a finished stream, so clients can distinguish clean completion from errors and resume the
+// stream from Offset later.
+type StreamSummary struct {
+	Reason   string        `json:"reason"`          // one of StopReason* constants
+	Bytes    int64         `json:"bytes"`           // file data bytes streamed
+	Offset   int64         `json:"offset"`          // file position the stream stopped at, the offset to resume from
+	Duration time.Duration `json:"duration"`        // nanoseconds in JSON
+	Error    string        `json:"error,omitempty"` // the error StreamTo() returned
+}
+
+// Summary returns the summary of the last finished stream of Listener, false when no stream has finished yet.
+func (bs *Listener) Summary() (StreamSummary, bool) {
+	bs.mu.Lock()
+	defer bs.mu.Unlock()
+
+	if bs.summary == nil {
+		return StreamSummary{}, false
+	}
+
+	return *bs.summary, true
+}
+
+func (bs *Listener) setSummary(reason string, bytes int64, duration time.Duration, err error) {
+	offset, _ := bs.file.Seek(0, io.SeekCurrent)
+
+	summary := &StreamSummary{Reason: reason, Bytes: bytes, Offset: offset, Duration: duration}
+	if err != nil {
+		summary.Error = err.Error()
+	}
+
+	bs.mu.Lock()
+	bs.summary = summary
+	bs.mu.Unlock()
+}
+
+// setSummaryTrailer sets HTTP trailer with the summary of <listener>'s stream. The trailer must be announced in
+// Trailer header before the response is written.
+func setSummaryTrailer(w http.ResponseWriter, listener *Listener) {
+	summary, ok := listener.Summary()
+	if !ok {
+		return
+	}
+
+	data, _ := json.Marshal(summary)
+	w.Header().Set(SummaryTrailer, string(data))
+}
+
+// writeSummaryEvent sends the summary of <listener>'s stream as the final 'end' Server-Sent Event.
+func writeSummaryEvent(w io.Writer, listener *Listener) error {
+	summary, ok := listener.Summary()
+	if !ok {
+		return nil
+	}
+
+	data, _ := json.Marshal(summary)
+	_, err := w.Write(append(append([]byte("event: end\ndata: "), data...), "\n\n"...))
+	return err
+}
+
+// summaryCloseReason returns JSON of the summary of <listener>'s stream that fits WebSocket close message. The error
+// text is cut to make it fit.
+func summaryCloseReason(listener *Listener) string {
+	summary, ok := listener.Summary()
+	if !ok {
+		return ""
+	}
+
+	for {
+		data, _ := json.Marshal(summary)
+		if len(data) <= maxCloseReason || summary.Error == "" {
+			return string(data)
+		}
+
+		cut := len(data) - maxCloseReason
+		if cut > len(summary.Error) {
+			cut = len(summary.Error)
+		}
+		summary.Error = summary.Error[:len(summary.Error)-cut]
+	}
+}
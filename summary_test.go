@@ -0,0 +1,89 @@
+package file_streamer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestListenerSummary(t *testing.T) {
+	s := startTestStreamer(t)
+	listener := NewListener(openTestFile(t, createTestFile(t, "line\n")), bufio.NewWriter(ioutil.Discard))
+
+	if _, ok := listener.Summary(); ok {
+		t.Error("summary is available before the stream")
+	}
+
+	catFile(t, s, listener)
+
+	summary, ok := listener.Summary()
+	if !ok {
+		t.Fatal("summary is not available after the stream")
+	}
+	if summary.Reason != StopReasonClosed || summary.Bytes != 5 || summary.Offset != 5 || summary.Error != "" {
+		t.Errorf("summary is %+v, want closed stream of 5 bytes", summary)
+	}
+}
+
+func TestStreamHTTPTrailer(t *testing.T) {
+	s := startTestStreamer(t)
+	server := streamHTTPServer(t, s, createTestFile(t, "data\n"), HTTPStreamOptions{Timeout: 10 * time.Millisecond, Trailer: true})
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = ioutil.ReadAll(resp.Body) // trailers are available after the body
+	resp.Body.Close()
+
+	var summary StreamSummary
+	if err = json.Unmarshal([]byte(resp.Trailer.Get(SummaryTrailer)), &summary); err != nil {
+		t.Fatalf("bad %s trailer %q: %v", SummaryTrailer, resp.Trailer.Get(SummaryTrailer), err)
+	}
+	if summary.Reason != StopReasonTimeout || summary.Offset != 5 {
+		t.Errorf("summary is %+v, want timed out stream at offset 5", summary)
+	}
+}
+
+func TestStreamSSETrailer(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "data\n")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		StreamSSE(w, r, name, s, HTTPStreamOptions{Timeout: 10 * time.Millisecond, Trailer: true})
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	events := strings.Split(strings.TrimSuffix(string(body), "\n\n"), "\n\n")
+	last := events[len(events)-1]
+	if len(events) != 2 || !strings.HasPrefix(last, "event: end\ndata: {\"reason\":\"timeout\"") {
+		t.Errorf("events are %q, want data event and 'end' event", events)
+	}
+}
+
+func TestSummaryCloseReasonFits(t *testing.T) {
+	listener := NewListener(openTestFile(t, createTestFile(t, "")), bufio.NewWriter(ioutil.Discard))
+	listener.setSummary(StopReasonError, 1<<40, time.Hour, errors.New(strings.Repeat("very long error ", 20)))
+
+	reason := summaryCloseReason(listener)
+	if len(reason) > maxCloseReason {
+		t.Errorf("close reason is %d bytes long, more than %d", len(reason), maxCloseReason)
+	}
+
+	var summary StreamSummary
+	if err := json.Unmarshal([]byte(reason), &summary); err != nil || summary.Bytes != 1<<40 {
+		t.Errorf("bad close reason %q: %v", reason, err)
+	}
+}
@@ -0,0 +1,243 @@
+package file_streamer
+
+import (
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// Syslog facilities (RFC5424, section 6.2.1)
+const (
+	SyslogKern = iota
+	SyslogUser
+	SyslogMail
+	SyslogDaemon
+	SyslogAuth
+	SyslogSyslog
+	SyslogLPR
+	SyslogNews
+	SyslogUUCP
+	SyslogCron
+	SyslogAuthPriv
+	SyslogFTP
+)
+
+// Syslog local use facilities
+const (
+	SyslogLocal0 = iota + 16
+	SyslogLocal1
+	SyslogLocal2
+	SyslogLocal3
+	SyslogLocal4
+	SyslogLocal5
+	SyslogLocal6
+	SyslogLocal7
+)
+
+// Syslog severities (RFC5424, section 6.2.1)
+const (
+	SyslogEmergency = iota
+	SyslogAlert
+	SyslogCritical
+	SyslogError
+	SyslogWarning
+	SyslogNotice
+	SyslogInfo
+	SyslogDebug
+)
+
+var (
+	// ErrSyslogNetwork is returned by NewSyslogWriter() for networks other than UDP and TCP.
+	ErrSyslogNetwork = errors.New("syslog network must be udp or tcp")
+
+	// ErrSyslogPriority is returned by NewSyslogWriter() for facilities out of 0-23 range and severities out of 0-7.
+	ErrSyslogPriority = errors.New("syslog facility must be 0-23 and severity 0-7")
+)
+
+// SyslogOptions configures SyslogWriter.
+type SyslogOptions struct {
+	Network string // "udp" or "tcp" (and their "4" and "6" variants)
+	Addr    string
+
+	// SyslogUser and SyslogNotice when both are zero: kern.emerg messages come from the kernel only, so zero values
+	// mean 'not set'.
+	Facility int
+	Severity int
+
+	Hostname string // os.Hostname() by default
+	AppName  string // "file-streamer" by default
+	ProcID   string // current process ID by default
+	MsgID    string // '-' (no value) by default
+
+	Clock Clock // source of message timestamps, RealClock by default
+}
+
+// SyslogWriter frames written lines as RFC5424 syslog messages and sends them over UDP (a datagram per message) or TCP
+// (with octet counting framing of RFC6587), so legacy syslog infrastructure can receive tailed files directly.
+//
+// An incomplete line is held until the rest of it is written or Flush() is called. TCP connection is re-established on
+// the next write after an error.
+type SyslogWriter struct {
+	options SyslogOptions
+	header  []byte // '<PRI>1 ' part of messages
+	tail    []byte // ' HOSTNAME APP-NAME PROCID MSGID -' part of messages following the timestamp
+	isTCP   bool
+
+	mu      sync.Mutex
+	conn    net.Conn
+	lines   lineSplitter
+	message []byte // reusable message buffer
+}
+
+// NewSyslogWriter connects to syslog server described by <options>. Returns ErrSyslogNetwork for networks other than
+// UDP and TCP, ErrSyslogPriority for unknown facilities and severities.
+func NewSyslogWriter(options SyslogOptions) (*SyslogWriter, error) {
+	var isTCP bool
+	switch options.Network {
+	case "udp", "udp4", "udp6":
+	case "tcp", "tcp4", "tcp6":
+		isTCP = true
+	default:
+		return nil, ErrSyslogNetwork
+	}
+
+	if options.Facility < SyslogKern || options.Facility > SyslogLocal7 || options.Severity < SyslogEmergency || options.Severity > SyslogDebug {
+		return nil, ErrSyslogPriority
+	}
+	if options.Facility == SyslogKern && options.Severity == SyslogEmergency {
+		options.Facility, options.Severity = SyslogUser, SyslogNotice
+	}
+
+	if options.Hostname == "" {
+		options.Hostname, _ = os.Hostname()
+	}
+	if options.AppName == "" {
+		options.AppName = "file-streamer"
+	}
+	if options.ProcID == "" {
+		options.ProcID = strconv.Itoa(os.Getpid())
+	}
+	if options.Clock == nil {
+		options.Clock = RealClock
+	}
+
+	w := &SyslogWriter{
+		options: options,
+		header:  []byte("<" + strconv.Itoa(options.Facility*8+options.Severity) + ">1 "),
+		tail: []byte(" " + syslogField(options.Hostname) + " " + syslogField(options.AppName) + " " +
+			syslogField(options.ProcID) + " " + syslogField(options.MsgID) + " -"),
+		isTCP: isTCP,
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// syslogField returns <value> as a header field: NILVALUE for empty ones, printable ASCII only otherwise.
+func syslogField(value string) string {
+	if value == "" {
+		return "-"
+	}
+
+	field := []byte(value)
+	for i, c := range field {
+		if c < 33 || c > 126 {
+			field[i] = '_'
+		}
+	}
+
+	return string(field)
+}
+
+func (w *SyslogWriter) connect() error {
+	conn, err := net.Dial(w.options.Network, w.options.Addr)
+	if err != nil {
+		return err
+	}
+
+	w.conn = conn
+	return nil
+}
+
+// Write sends each complete line of <p> as a separate syslog message.
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.lines.split(p, w.send); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Flush sends the incomplete line held by writer.
+func (w *SyslogWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.lines.flush(w.send)
+}
+
+// Close flushes the incomplete line and closes connection to syslog server.
+func (w *SyslogWriter) Close() error {
+	err := w.Flush()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		if closeErr := w.conn.Close(); err == nil {
+			err = closeErr
+		}
+		w.conn = nil
+	}
+
+	return err
+}
+
+// format builds RFC5424 message with <line> as MSG part.
+func (w *SyslogWriter) format(line []byte) []byte {
+	msg := append(w.message[:0], w.header...)
+	msg = w.options.Clock.Now().UTC().AppendFormat(msg, "2006-01-02T15:04:05.000000Z07:00")
+	msg = append(msg, w.tail...)
+	if len(line) != 0 {
+		msg = append(msg, ' ')
+		msg = append(msg, line...)
+	}
+
+	w.message = msg
+	return msg
+}
+
+func (w *SyslogWriter) send(line []byte) error {
+	msg := w.format(line)
+
+	if w.isTCP {
+		framed := make([]byte, 0, len(msg)+8)
+		framed = strconv.AppendInt(framed, int64(len(msg)), 10)
+		framed = append(framed, ' ')
+		msg = append(framed, msg...)
+	}
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.conn.Write(msg); err != nil {
+		if w.isTCP {
+			_ = w.conn.Close()
+			w.conn = nil
+		}
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fixedClock always returns the same time: message timestamps stay predictable.
+type fixedClock struct {
+	Clock
+	now time.Time
+}
+
+func (c fixedClock) Now() time.Time {
+	return c.now
+}
+
+func newTestSyslogOptions(network, addr string) SyslogOptions {
+	return SyslogOptions{
+		Network:  network,
+		Addr:     addr,
+		Facility: SyslogLocal3,
+		Severity: SyslogWarning,
+		Hostname: "web 1",
+		AppName:  "tail",
+		ProcID:   "42",
+		Clock:    fixedClock{RealClock, time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)},
+	}
+}
+
+const testSyslogPrefix = "<156>1 2020-01-02T03:04:05.000006Z web_1 tail 42 - - "
+
+func TestSyslogWriterUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := NewSyslogWriter(newTestSyslogOptions("udp", conn.LocalAddr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("first\nsec"))
+	w.Write([]byte("ond\n"))
+
+	buf := make([]byte, 1024)
+	for _, want := range []string{"first", "second"} {
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := string(buf[:n]); got != testSyslogPrefix+want {
+			t.Errorf("received %q, want %q", got, testSyslogPrefix+want)
+		}
+	}
+}
+
+func TestSyslogWriterTCPOctetCounting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for {
+			length, err := r.ReadString(' ')
+			if err != nil {
+				close(received)
+				return
+			}
+
+			size, _ := strconv.Atoi(strings.TrimSuffix(length, " "))
+			msg := make([]byte, size)
+			if _, err = io.ReadFull(r, msg); err != nil {
+				close(received)
+				return
+			}
+			received <- string(msg)
+		}
+	}()
+
+	s := startTestStreamer(t)
+	name := createTestFile(t, "line one\nline two\n")
+
+	w, err := NewSyslogWriter(newTestSyslogOptions("tcp", ln.Addr().String()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	catFile(t, s, NewListener(openTestFile(t, name), bufio.NewWriter(w)))
+	w.Close()
+
+	var messages []string
+	for msg := range received {
+		messages = append(messages, msg)
+	}
+
+	if len(messages) != 2 || messages[0] != testSyslogPrefix+"line one" || messages[1] != testSyslogPrefix+"line two" {
+		t.Errorf("received %q", messages)
+	}
+}
+
+func TestNewSyslogWriterNetwork(t *testing.T) {
+	if _, err := NewSyslogWriter(SyslogOptions{Network: "unix"}); err != ErrSyslogNetwork {
+		t.Errorf("got %v for unix network, want %v", err, ErrSyslogNetwork)
+	}
+}
+
+func TestNewSyslogWriterPriority(t *testing.T) {
+	for _, options := range []SyslogOptions{
+		{Network: "udp", Facility: SyslogLocal7 + 1},
+		{Network: "udp", Severity: SyslogDebug + 1},
+		{Network: "udp", Facility: -1},
+	} {
+		if _, err := NewSyslogWriter(options); err != ErrSyslogPriority {
+			t.Errorf("got %v for facility %d and severity %d, want %v", err, options.Facility, options.Severity, ErrSyslogPriority)
+		}
+	}
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := NewSyslogWriter(SyslogOptions{Network: "udp", Addr: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("line\n"))
+
+	buf := make([]byte, 1024)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := string(buf[:n]); !strings.HasPrefix(got, "<13>1 ") {
+		t.Errorf("received %q, want user.notice priority <13> by default", got)
+	}
+}
@@ -0,0 +1,81 @@
+package file_streamer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// ErrTailReaderClosed is returned by Read() of the reader closed by Close(), see NewTailReader().
+var ErrTailReaderClosed = errors.New("tail reader is closed")
+
+// tailReader reads chunks of Streamer.Follow().
+type tailReader struct {
+	chunks  <-chan Chunk
+	cancel  context.CancelFunc
+	pending []byte // the rest of the last chunk
+	err     error  // returned by all reads after the end of stream
+	closed  uint32 // 1 after Close(), updated atomically
+}
+
+// NewTailReader returns a reader of the file at <path> that follows the file like 'tail -f': Read blocks until new
+// data is written to the file, so the reader can be passed to json.Decoder, bufio.Scanner or io.Copy as is. Read
+// returns io.EOF when the stream stops for a regular reason: the file is removed or options.Timeout expires. Other
+// errors of the stream are returned as is.
+//
+// Close stops the stream and unblocks pending Read, it returns ErrTailReaderClosed then. See Streamer.Follow() for
+// options.
+func NewTailReader(streamer *Streamer, path string, options FollowOptions) (io.ReadCloser, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	chunks, err := streamer.Follow(ctx, path, options)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &tailReader{chunks: chunks, cancel: cancel}, nil
+}
+
+func (r *tailReader) Read(p []byte) (int, error) {
+	if atomic.LoadUint32(&r.closed) == 1 {
+		return 0, ErrTailReaderClosed
+	}
+
+	for len(r.pending) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+
+		chunk, ok := <-r.chunks
+		switch {
+		case atomic.LoadUint32(&r.closed) == 1:
+			r.err = ErrTailReaderClosed
+		case !ok:
+			r.err = io.EOF
+		case chunk.Err != nil:
+			r.err = chunk.Err
+		default:
+			r.pending = chunk.Data
+		}
+	}
+
+	n := copy(p, r.pending)
+	r.pending = r.pending[n:]
+	return n, nil
+}
+
+// Close stops the stream and waits for the file to be closed.
+func (r *tailReader) Close() error {
+	if !atomic.CompareAndSwapUint32(&r.closed, 0, 1) {
+		return nil
+	}
+
+	// the channel is closed when the stream ends, the chunk that was being sent is dropped
+	r.cancel()
+	for range r.chunks {
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+)
+
+func TestTailReader(t *testing.T) {
+	s := startTestStreamer(t)
+	name := createTestFile(t, "first\n")
+
+	reader, err := NewTailReader(s, name, FollowOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scanner := bufio.NewScanner(reader)
+	if !scanner.Scan() || scanner.Text() != "first" {
+		t.Fatalf("scanned %q (%v), want %q", scanner.Text(), scanner.Err(), "first")
+	}
+
+	for i := 0; len(s.ActiveStreams()) == 0 && i < 2000; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	appendToFile(t, name, "second\n")
+
+	if !scanner.Scan() || scanner.Text() != "second" {
+		t.Fatalf("scanned %q (%v), want %q", scanner.Text(), scanner.Err(), "second")
+	}
+
+	if err = reader.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reader.Read(make([]byte, 1)); err != ErrTailReaderClosed {
+		t.Errorf("read after Close() returned %v, want %v", err, ErrTailReaderClosed)
+	}
+}
+
+func TestTailReaderEOF(t *testing.T) {
+	s := startTestStreamer(t)
+
+	reader, err := NewTailReader(s, createTestFile(t, "data"), FollowOptions{Timeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil || string(data) != "data" {
+		t.Errorf("read %q (%v), want %q", data, err, "data")
+	}
+	if _, err = reader.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("read after the end of stream returned %v, want io.EOF", err)
+	}
+}
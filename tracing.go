@@ -0,0 +1,45 @@
+package file_streamer
+
+import "context"
+
+// Tracer starts tracing spans around stream lifecycle. It is a tiny subset of OpenTelemetry API, so Streamer does not
+// depend on any tracing library. OpenTelemetry adapter takes a few lines:
+//
+//	type otelTracer struct{ trace.Tracer }
+//
+//	func (t otelTracer) Start(ctx context.Context, name string) (context.Context, file_streamer.Span) {
+//		ctx, span := t.Tracer.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+//
+// where otelSpan implements SetAttribute() with span.SetAttributes(attribute.String(...)) and so on.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a single traced operation.
+type Span interface {
+	// SetAttribute sets span attribute. <value> is a string, bool, int or int64.
+	SetAttribute(key string, value interface{})
+	RecordError(err error)
+	End()
+}
+
+// Names of spans created by Streamer
+const (
+	SpanStream  = "file_streamer.stream"   // the whole StreamToContext() call
+	SpanCatchUp = "file_streamer.catch_up" // the first read: data existing in file when stream started
+	SpanFlush   = "file_streamer.flush"    // each read of new file data and its flush to listener's writer
+)
+
+type noopTracer struct{}
+
+func (noopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{}
+}
+
+type noopSpan struct{}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
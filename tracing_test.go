@@ -0,0 +1,111 @@
+package file_streamer
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordedSpan struct {
+	name       string
+	parent     string
+	attributes map[string]interface{}
+	ended      bool
+}
+
+type spanNameKey struct{}
+
+type recordingTracer struct {
+	mu    sync.Mutex
+	spans []*recordedSpan
+}
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	parent, _ := ctx.Value(spanNameKey{}).(string)
+	span := &recordedSpan{name: name, parent: parent, attributes: make(map[string]interface{})}
+	t.spans = append(t.spans, span)
+
+	return context.WithValue(ctx, spanNameKey{}, name), recordingSpan{t, span}
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	span   *recordedSpan
+}
+
+func (s recordingSpan) SetAttribute(key string, value interface{}) {
+	s.tracer.mu.Lock()
+	s.span.attributes[key] = value
+	s.tracer.mu.Unlock()
+}
+
+func (s recordingSpan) RecordError(err error) {
+	s.SetAttribute("error", err.Error())
+}
+
+func (s recordingSpan) End() {
+	s.tracer.mu.Lock()
+	s.span.ended = true
+	s.tracer.mu.Unlock()
+}
+
+func TestStreamToContextTracing(t *testing.T) {
+	s := startTestStreamer(t)
+	tracer := &recordingTracer{}
+	s.SetTracer(tracer)
+
+	listener := NewListener(openTestFile(t, createTestFile(t, "0123456789")), bufio.NewWriter(ioutil.Discard))
+	listener.Close()
+
+	ctx := context.WithValue(context.Background(), spanNameKey{}, "http.request")
+	if err := s.StreamToContext(ctx, listener, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tracer.spans) != 2 {
+		t.Fatalf("got %d spans, want 2", len(tracer.spans))
+	}
+
+	stream, catchUp := tracer.spans[0], tracer.spans[1]
+	if stream.name != SpanStream || stream.parent != "http.request" || !stream.ended {
+		t.Errorf("unexpected stream span %+v", stream)
+	}
+	if stream.attributes["stop_reason"] != StopReasonClosed {
+		t.Errorf("stop reason is %v", stream.attributes["stop_reason"])
+	}
+
+	if catchUp.name != SpanCatchUp || catchUp.parent != SpanStream || !catchUp.ended {
+		t.Errorf("unexpected catch up span %+v", catchUp)
+	}
+	if catchUp.attributes["bytes"] != int64(10) {
+		t.Errorf("catch up span reports %v bytes", catchUp.attributes["bytes"])
+	}
+}
+
+func TestStreamToContextCancel(t *testing.T) {
+	s := startTestStreamer(t)
+	listener := NewListener(openTestFile(t, createTestFile(t, "data")), bufio.NewWriter(ioutil.Discard))
+	defer listener.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error)
+	go func() { result <- s.StreamToContext(ctx, listener, 0) }()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case err := <-result:
+		if err != context.Canceled {
+			t.Errorf("got %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream was not stopped by context")
+	}
+}
@@ -0,0 +1,128 @@
+package file_streamer
+
+import (
+	"golang.org/x/text/transform"
+	"unicode/utf8"
+)
+
+// chunkTransformer applies transform.Transformer to a sequence of chunks read from a growing file.
+//
+// transform.Reader can't be used here: it treats the first io.EOF from the file as the end of data, while for us it
+// only means 'no new data yet'. Multi-byte sequences split between two reads are kept in <pending> until the rest of
+// the sequence is written into the file. The incomplete sequence left at the end of the last chunk is silently dropped
+// when the stream ends.
+type chunkTransformer struct {
+	transformer transform.Transformer
+
+	pending []byte // the beginning of incomplete sequence left from previous chunk
+	dst     []byte // reusable buffer for transformed data
+}
+
+func newChunkTransformer(t transform.Transformer) *chunkTransformer {
+	t.Reset()
+
+	return &chunkTransformer{
+		transformer: t,
+		dst:         make([]byte, 4096),
+	}
+}
+
+// reset drops incomplete sequence left from previous chunk and resets transformer state.
+func (c *chunkTransformer) reset() {
+	c.pending = nil
+	c.transformer.Reset()
+}
+
+// transform returns transformed data of <chunk>. The result is valid until the next transform() call.
+func (c *chunkTransformer) transform(chunk []byte) ([]byte, error) {
+	src := chunk
+	if len(c.pending) != 0 {
+		src = append(c.pending, chunk...)
+		c.pending = nil
+	}
+
+	var result []byte
+	for len(src) > 0 {
+		nDst, nSrc, err := c.transformer.Transform(c.dst, src, false)
+		result = append(result, c.dst[:nDst]...)
+		src = src[nSrc:]
+
+		switch err {
+		case nil:
+			if nDst == 0 && nSrc == 0 {
+				// transformer wants more data, but did not say so
+				c.pending = append([]byte(nil), src...)
+				return result, nil
+			}
+
+		case transform.ErrShortDst:
+			if nDst == 0 && nSrc == 0 {
+				// even a single sequence does not fit into destination buffer
+				c.dst = make([]byte, 2*len(c.dst))
+			}
+
+		case transform.ErrShortSrc:
+			c.pending = append([]byte(nil), src...)
+			return result, nil
+
+		default:
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// wholeRunes is a transformer that passes data as is, but holds incomplete UTF-8 character at the end of data.
+type wholeRunes struct {
+	transform.NopResetter
+}
+
+func (wholeRunes) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	n := len(src)
+	if !atEOF {
+		for i := len(src) - 1; i >= 0 && i > len(src)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(src[i]) {
+				if !utf8.FullRune(src[i:]) {
+					n = i
+				}
+				break
+			}
+		}
+	}
+
+	nDst = copy(dst, src[:n])
+	switch {
+	case nDst < n:
+		return nDst, nDst, transform.ErrShortDst
+	case n < len(src):
+		return nDst, nDst, transform.ErrShortSrc
+	}
+
+	return nDst, nDst, nil
+}
+
+// newTransformer builds transformation chain for listener's data stream: charset conversion (or UTF-8 characters
+// holder for text encoders) followed by user's transformers. Returns nil when there is nothing to transform.
+//
+// Must be called under listener's lock.
+func (bs *Listener) newTransformer() *chunkTransformer {
+	var chain []transform.Transformer
+
+	if bs.charset != nil {
+		chain = append(chain, bs.charset.NewDecoder())
+	} else if _, isText := bs.encoder.(textEncoder); isText {
+		chain = append(chain, wholeRunes{})
+	}
+
+	chain = append(chain, bs.transformers...)
+
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return newChunkTransformer(chain[0])
+	default:
+		return newChunkTransformer(transform.Chain(chain...))
+	}
+}
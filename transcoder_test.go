@@ -0,0 +1,96 @@
+package file_streamer
+
+import (
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"testing"
+)
+
+const transcoderSample = "ログファイル: 日本語のテキスト, half-width ｶﾀｶﾅ and ASCII"
+
+func shiftJIS(t *testing.T, text string) []byte {
+	encoded, err := japanese.ShiftJIS.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return encoded
+}
+
+func transformChunks(t *testing.T, transformer *chunkTransformer, chunks ...[]byte) string {
+	var result []byte
+	for _, chunk := range chunks {
+		transformed, err := transformer.transform(chunk)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		result = append(result, transformed...)
+	}
+
+	return string(result)
+}
+
+func TestChunkTransformerSplitAtEveryByte(t *testing.T) {
+	encoded := shiftJIS(t, transcoderSample)
+
+	for i := 0; i <= len(encoded); i++ {
+		transformer := newChunkTransformer(japanese.ShiftJIS.NewDecoder())
+
+		decoded := transformChunks(t, transformer, encoded[:i], encoded[i:])
+		if decoded != transcoderSample {
+			t.Errorf("split at %d: decoded %q, want %q", i, decoded, transcoderSample)
+		}
+	}
+}
+
+func TestChunkTransformerByteByByte(t *testing.T) {
+	encoded := shiftJIS(t, transcoderSample)
+	transformer := newChunkTransformer(japanese.ShiftJIS.NewDecoder())
+
+	var chunks [][]byte
+	for i := range encoded {
+		chunks = append(chunks, encoded[i:i+1])
+	}
+
+	if decoded := transformChunks(t, transformer, chunks...); decoded != transcoderSample {
+		t.Errorf("decoded %q, want %q", decoded, transcoderSample)
+	}
+}
+
+// Destination buffer too small even for a single character has to grow.
+func TestChunkTransformerGrowsDestination(t *testing.T) {
+	transformer := newChunkTransformer(japanese.ShiftJIS.NewDecoder())
+	transformer.dst = make([]byte, 1)
+
+	encoded := shiftJIS(t, transcoderSample)
+	if decoded := transformChunks(t, transformer, encoded); decoded != transcoderSample {
+		t.Errorf("decoded %q, want %q", decoded, transcoderSample)
+	}
+
+	if len(transformer.dst) < 3 {
+		t.Errorf("destination buffer did not grow: %d bytes", len(transformer.dst))
+	}
+}
+
+func TestChunkTransformerLatin1(t *testing.T) {
+	transformer := newChunkTransformer(charmap.ISO8859_1.NewDecoder())
+
+	decoded := transformChunks(t, transformer, []byte("caf\xe9 "), []byte("na\xefve"))
+	if decoded != "café naïve" {
+		t.Errorf("decoded %q, want %q", decoded, "café naïve")
+	}
+}
+
+func TestChunkTransformerKeepsIncompleteTail(t *testing.T) {
+	encoded := shiftJIS(t, "日本")
+	transformer := newChunkTransformer(japanese.ShiftJIS.NewDecoder())
+
+	if decoded := transformChunks(t, transformer, encoded[:3]); decoded != "日" {
+		t.Errorf("decoded %q, want %q", decoded, "日")
+	}
+
+	if len(transformer.pending) != 1 {
+		t.Errorf("%d bytes pending, want 1", len(transformer.pending))
+	}
+}
@@ -0,0 +1,209 @@
+package file_streamer
+
+import (
+	"github.com/fsnotify/fsnotify"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// TreeWatcher watches a directory tree for files matching include/exclude rules. New subdirectories are watched as
+// soon as they appear, so "tail anything under /var/log/app/" works without restarts.
+type TreeWatcher struct {
+	root    string
+	include []string
+	exclude []string
+	logger  *log.Logger
+
+	watcher Watcher
+	files   chan string
+	known   map[string]empty // files already sent to Files()
+
+	done      chan empty
+	closeOnce sync.Once
+	stopped   chan empty
+}
+
+// WatchTree starts watching directory tree under <root>. Files matching any of <include> glob patterns (all files,
+// when there are no patterns) and none of <exclude> ones are sent to TreeWatcher.Files(): existing ones first, then
+// new ones as they are created. Directories matching <exclude> are not watched at all.
+//
+// Patterns (see filepath.Match) are matched against both the path relative to <root> and the base name, so '*.log'
+// matches log files at any depth, while 'nginx/*.log' matches the ones in 'nginx' subdirectory only.
+//
+// TreeWatcher uses a separate Watcher created by Streamer's watcher factory. It does not need Streamer to be running.
+func (s *Streamer) WatchTree(root string, include, exclude []string) (*TreeWatcher, error) {
+	for _, pattern := range append(include, exclude...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	factory, logger := s.watcherFactory, s.logger
+	s.mu.Unlock()
+
+	watcher, err := factory()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TreeWatcher{
+		root:    filepath.Clean(root),
+		include: include,
+		exclude: exclude,
+		logger:  logger,
+
+		watcher: watcher,
+		files:   make(chan string, 100),
+		known:   make(map[string]empty),
+
+		done:    make(chan empty),
+		stopped: make(chan empty),
+	}
+
+	go t.run()
+	return t, nil
+}
+
+// Files returns channel of matched file paths. The channel is closed after Close().
+func (t *TreeWatcher) Files() <-chan string {
+	return t.files
+}
+
+// Close stops watching the tree.
+func (t *TreeWatcher) Close() error {
+	t.closeOnce.Do(func() { close(t.done) })
+	<-t.stopped
+
+	return nil
+}
+
+func (t *TreeWatcher) matches(path string, patterns []string) bool {
+	rel, err := filepath.Rel(t.root, path)
+	if err != nil {
+		rel = path
+	}
+
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, rel); ok {
+			return true
+		}
+		if ok, _ := filepath.Match(pattern, filepath.Base(path)); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *TreeWatcher) isIncluded(path string) bool {
+	if t.matches(path, t.exclude) {
+		return false
+	}
+
+	return len(t.include) == 0 || t.matches(path, t.include)
+}
+
+func (t *TreeWatcher) run() {
+	defer close(t.stopped)
+	defer close(t.files)
+
+	// fsnotify can't close watcher concurrently with other calls, so it is done here, after the last Add()
+	defer func() {
+		_ = t.watcher.Close()
+		for range t.watcher.Events() {
+		}
+	}()
+	go func() {
+		for range t.watcher.Errors() {
+		}
+	}()
+
+	if !t.addTree(t.root) {
+		return
+	}
+
+	for {
+		select {
+		case event, isOpen := <-t.watcher.Events():
+			if !isOpen {
+				return
+			}
+			if !t.handle(event) {
+				return
+			}
+		case <-t.done:
+			return
+		}
+	}
+}
+
+// handle processes file system event. Returns false when watcher was closed.
+func (t *TreeWatcher) handle(event fsnotify.Event) bool {
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		delete(t.known, event.Name) // a file created again at the same path is a new file
+		return true
+	}
+
+	if event.Op&fsnotify.Create == 0 {
+		return true
+	}
+
+	info, err := os.Stat(event.Name)
+	if err != nil {
+		return true // already removed
+	}
+
+	if info.IsDir() {
+		return t.addTree(event.Name)
+	}
+
+	return t.found(event.Name)
+}
+
+// addTree watches directories of the tree under <dir> and sends files found there. Files created before the watch
+// was added would be missed otherwise. Returns false when watcher was closed.
+func (t *TreeWatcher) addTree(dir string) bool {
+	isOpen := true
+	_ = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // skip unreadable entries
+		}
+
+		if info.IsDir() {
+			if path != t.root && t.matches(path, t.exclude) {
+				return filepath.SkipDir
+			}
+
+			if err = t.watcher.Add(path); err != nil {
+				t.logger.Printf("Failed to watch directory '%s': %v", path, err)
+			}
+			return nil
+		}
+
+		if isOpen = t.found(path); !isOpen {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+
+	return isOpen
+}
+
+// found sends <path> to Files() when it matches the rules and was not sent before. Returns false when watcher was
+// closed.
+func (t *TreeWatcher) found(path string) bool {
+	if _, isKnown := t.known[path]; isKnown || !t.isIncluded(path) {
+		return true
+	}
+	t.known[path] = empty{}
+
+	select {
+	case t.files <- path:
+		return true
+	case <-t.done:
+		return false
+	}
+}
@@ -0,0 +1,93 @@
+package file_streamer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// expectFiles reads <n> paths from TreeWatcher and checks they are exactly <want>.
+func expectFiles(t *testing.T, tw *TreeWatcher, want ...string) {
+	t.Helper()
+
+	got := make(map[string]bool)
+	for range want {
+		select {
+		case path := <-tw.Files():
+			got[path] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for files, got %v, want %v", got, want)
+		}
+	}
+
+	for _, path := range want {
+		if !got[path] {
+			t.Fatalf("got files %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWatchTree(t *testing.T) {
+	root, err := ioutil.TempDir("", "tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	mustWrite := func(path string) string {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("data\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	existing := mustWrite("app/existing.log")
+	mustWrite("app/existing.txt")
+	mustWrite("tmp/skipped.log")
+
+	tw, err := New(nil).WatchTree(root, []string{"*.log"}, []string{"tmp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tw.Close()
+
+	expectFiles(t, tw, existing)
+
+	// a new file in a new nested directory must be found, files in excluded directories must not
+	mustWrite("tmp/new.log")
+	nested := mustWrite("app/nested/deeper/new.log")
+	expectFiles(t, tw, nested)
+
+	// a file created again after removal is a new file
+	if err := os.Remove(existing); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	mustWrite("app/existing.log")
+	expectFiles(t, tw, existing)
+
+	select {
+	case path := <-tw.Files():
+		t.Fatalf("unexpected file '%s'", path)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, isOpen := <-tw.Files(); isOpen {
+		t.Error("Files() channel is not closed after Close()")
+	}
+}
+
+func TestWatchTreeBadPattern(t *testing.T) {
+	if _, err := New(nil).WatchTree(os.TempDir(), nil, []string{"["}); err != filepath.ErrBadPattern {
+		t.Errorf("got error %v, want %v", err, filepath.ErrBadPattern)
+	}
+}
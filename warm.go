@@ -0,0 +1,262 @@
+package file_streamer
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTailLines   = 1000             // lines kept for each watched file by default
+	warmRescanInterval = 10 * time.Second // how often patterns of watched files are matched again
+)
+
+// SetWatchedFiles pre-registers files to be watched since Start(), independently of listeners: Streamer streams each
+// file matched by <patterns> (file paths or glob patterns, see filepath.Match) into a buffer of its last <tailLines>
+// lines (1000 when 0), so Tail() has them instantly when the first client connects. Line indexes of the files (see
+// SetLineIndexInterval()) are kept warm too. Patterns are matched again every 10 seconds, so rotated and new files are
+// picked up.
+//
+// Can't be changed while Streamer is running, returns ErrRunning then.
+func (s *Streamer) SetWatchedFiles(patterns []string, tailLines int) error {
+	for _, pattern := range patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return err
+		}
+	}
+
+	if tailLines <= 0 {
+		tailLines = defaultTailLines
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state != stateStopped {
+		return ErrRunning
+	}
+
+	s.watchedPatterns = append([]string(nil), patterns...)
+	s.tailLines = tailLines
+	return nil
+}
+
+// Tail returns up to <lines> last lines of the file at <path> pre-registered with SetWatchedFiles(), false when the
+// file is not watched.
+func (s *Streamer) Tail(path string, lines int) ([]string, bool) {
+	path = s.normalizePath(path)
+
+	s.mu.Lock()
+	watched, exists := s.warm[path]
+	s.mu.Unlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	return watched.ring.last(lines), true
+}
+
+type warmFile struct {
+	listener *Listener
+	ring     *tailRing
+}
+
+// startWarmFiles starts streams of watched files, called by Start().
+func (s *Streamer) startWarmFiles() {
+	if len(s.watchedPatterns) == 0 {
+		return
+	}
+
+	s.warmDone = make(chan empty)
+	s.warmRescans = make(chan empty)
+	go s.rescanWarmFiles()
+}
+
+// stopWarmFiles stops streams of watched files, called by Stop() before it waits for the end of all streams.
+func (s *Streamer) stopWarmFiles() {
+	if s.warmDone == nil {
+		return
+	}
+
+	close(s.warmDone)
+	<-s.warmRescans
+
+	s.mu.Lock()
+	for _, watched := range s.warm {
+		watched.listener.Close()
+	}
+	s.mu.Unlock()
+
+	s.warmStreams.Wait()
+	s.warmDone = nil
+}
+
+func (s *Streamer) rescanWarmFiles() {
+	defer close(s.warmRescans)
+
+	timer := s.clock.NewTimer(warmRescanInterval)
+	defer timer.Stop()
+
+	for {
+		s.scanWarmFiles()
+
+		select {
+		case <-timer.C():
+			timer.Reset(warmRescanInterval)
+		case <-s.warmDone:
+			return
+		}
+	}
+}
+
+// scanWarmFiles starts streams of matched files that are not streamed yet.
+func (s *Streamer) scanWarmFiles() {
+	for _, pattern := range s.watchedPatterns {
+		matches, _ := filepath.Glob(pattern) // patterns were validated by SetWatchedFiles()
+
+		for _, path := range matches {
+			key := s.normalizePath(path)
+
+			s.mu.Lock()
+			_, isWatched := s.warm[key]
+			s.mu.Unlock()
+
+			if !isWatched {
+				s.startWarmFile(key, path)
+			}
+		}
+	}
+}
+
+func (s *Streamer) startWarmFile(key, path string) {
+	file, err := os.Open(path)
+	if err != nil {
+		s.logger.Printf("Failed to open watched file '%s': %v", path, err)
+		return
+	}
+
+	offset, err := tailOffset(file, s.tailLines)
+	if err == nil {
+		_, err = file.Seek(offset, io.SeekStart)
+	}
+	if err != nil {
+		file.Close()
+		s.logger.Printf("Failed to find the tail of watched file '%s': %v", path, err)
+		return
+	}
+
+	watched := &warmFile{ring: newTailRing(s.tailLines)}
+	watched.listener = NewListener(file, bufio.NewWriter(watched.ring))
+
+	s.mu.Lock()
+	s.warm[key] = watched
+	s.mu.Unlock()
+
+	s.warmStreams.Add(1)
+	go func() {
+		defer s.warmStreams.Done()
+		defer file.Close()
+
+		if err := s.StreamTo(watched.listener, 0); err != nil && err != ErrNotRunning {
+			s.logger.Printf("Watched file '%s' stream error: %v", path, err)
+		}
+
+		// the file was removed: a new file at the same path is picked up on the next rescan
+		s.mu.Lock()
+		if s.warm[key] == watched {
+			delete(s.warm, key)
+		}
+		s.mu.Unlock()
+	}()
+}
+
+// tailOffset returns the offset of the beginning of the last <lines> lines of <file>.
+func tailOffset(file *os.File, lines int) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+
+	buf := make([]byte, 64*1024)
+	found := 0
+	for end := size; end > 0; {
+		start := end - int64(len(buf))
+		if start < 0 {
+			start = 0
+		}
+
+		n, err := file.ReadAt(buf[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+
+		for i := n - 1; i >= 0; i-- {
+			// the line feed at the end of file finishes the last line, it does not start a new one
+			if buf[i] != '\n' || start+int64(i) == size-1 {
+				continue
+			}
+
+			if found++; found == lines {
+				return start + int64(i) + 1, nil
+			}
+		}
+
+		end = start
+	}
+
+	return 0, nil
+}
+
+// tailRing keeps the last lines of data written to it.
+type tailRing struct {
+	mu    sync.Mutex
+	lines []string
+	next  int // index of the next line to write
+	full  bool
+	split lineSplitter
+}
+
+func newTailRing(size int) *tailRing {
+	return &tailRing{lines: make([]string, size)}
+}
+
+func (r *tailRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.split.split(p, func(line []byte) error {
+		r.lines[r.next] = string(line)
+		if r.next++; r.next == len(r.lines) {
+			r.next, r.full = 0, true
+		}
+		return nil
+	})
+
+	return len(p), err
+}
+
+// last returns up to <n> last complete lines, the oldest first.
+func (r *tailRing) last(n int) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	available := r.next
+	if r.full {
+		available = len(r.lines)
+	}
+	if n > available || n <= 0 {
+		n = available
+	}
+
+	result := make([]string, n)
+	for i := range result {
+		result[i] = r.lines[(r.next-n+i+len(r.lines))%len(r.lines)]
+	}
+
+	return result
+}
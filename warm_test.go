@@ -0,0 +1,90 @@
+package file_streamer
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func waitTail(t *testing.T, s *Streamer, name string, want []string) {
+	t.Helper()
+
+	var lines []string
+	for i := 0; i < 2000; i++ {
+		lines, _ = s.Tail(name, 10)
+		if reflect.DeepEqual(lines, want) {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Fatalf("got tail %q, want %q", lines, want)
+}
+
+func TestWatchedFiles(t *testing.T) {
+	root, err := ioutil.TempDir("", "watched")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	name := filepath.Join(root, "app.log")
+	if err = ioutil.WriteFile(name, []byte("a\nb\nc\nd\ne\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(log.New(ioutil.Discard, "", 0))
+	if err = s.SetWatchedFiles([]string{"["}, 3); err != filepath.ErrBadPattern {
+		t.Errorf("bad pattern returned %v, want %v", err, filepath.ErrBadPattern)
+	}
+	if err = s.SetWatchedFiles([]string{filepath.Join(root, "*.log")}, 3); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = s.SetWatchedFiles(nil, 0); err != ErrRunning {
+		t.Errorf("change of running streamer returned %v, want %v", err, ErrRunning)
+	}
+
+	waitTail(t, s, name, []string{"c", "d", "e"})
+	for len(s.ActiveStreams()) != 1 {
+		time.Sleep(time.Millisecond) // the file is watched since its stream is active
+	}
+
+	appendToFile(t, name, "f\ng")
+	waitTail(t, s, name, []string{"d", "e", "f"}) // incomplete line is not in the tail yet
+
+	if _, isWatched := s.Tail(filepath.Join(root, "other.log"), 10); isWatched {
+		t.Error("not matched file is watched")
+	}
+
+	stopped := make(chan error, 1)
+	go func() { stopped <- s.Stop() }()
+	select {
+	case err = <-stopped:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("streamer with watched files did not stop")
+	}
+
+	if _, isWatched := s.Tail(name, 10); isWatched {
+		t.Error("file is still watched after Stop()")
+	}
+}
+
+func TestTailOffset(t *testing.T) {
+	for data, want := range map[string]int64{"": 0, "a\nb\nc\n": 2, "a\nb\nc": 2, "a\n": 0, "a\nb\nc\nd": 4} {
+		file := openTestFile(t, createTestFile(t, data))
+		if offset, err := tailOffset(file, 2); err != nil || offset != want {
+			t.Errorf("%q: got tail offset %d (%v), want %d", data, offset, err, want)
+		}
+	}
+}
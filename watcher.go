@@ -0,0 +1,43 @@
+package file_streamer
+
+import "github.com/fsnotify/fsnotify"
+
+// Watcher is a source of file change events for Streamer. By default Streamer uses fsNotify-based watcher (see
+// NewFSNotifyWatcher), replace it with a fake one (see streamertest.FakeWatcher) to test streaming without waiting
+// for real file system notifications. NewStandbyWatcher adds poll watcher to fsNotify-based one, so streams don't
+// stall on missed inotify events.
+//
+// Streamer reads Events() and Errors() until both channels are closed, so Close() must close them.
+type Watcher interface {
+	Add(name string) error
+	Remove(name string) error
+	Close() error
+
+	Events() <-chan fsnotify.Event
+	Errors() <-chan error
+}
+
+// WatcherFactory creates a new Watcher. Streamer calls it on each Start().
+type WatcherFactory func() (Watcher, error)
+
+type fsNotifyWatcher struct {
+	*fsnotify.Watcher
+}
+
+// NewFSNotifyWatcher creates Watcher on top of fsnotify: inotify on Linux, kqueue on BSD and MacOS X and so on.
+func NewFSNotifyWatcher() (Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	return fsNotifyWatcher{watcher}, nil
+}
+
+func (w fsNotifyWatcher) Events() <-chan fsnotify.Event {
+	return w.Watcher.Events
+}
+
+func (w fsNotifyWatcher) Errors() <-chan error {
+	return w.Watcher.Errors
+}
@@ -0,0 +1,216 @@
+package file_streamer
+
+import (
+	"bufio"
+	"github.com/gorilla/websocket"
+	"net/http"
+	"os"
+	"time"
+)
+
+// webSocketWriter sends each write to WebSocket client as a separate message.
+type webSocketWriter struct {
+	msgType      int
+	conn         *websocket.Conn
+	writeTimeout time.Duration // 0 means 'no write deadline'
+}
+
+func (ws *webSocketWriter) Write(p []byte) (int, error) {
+	if ws.writeTimeout != 0 {
+		if err := ws.conn.SetWriteDeadline(time.Now().Add(ws.writeTimeout)); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := ws.conn.WriteMessage(ws.msgType, p); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// NewWSWriter creates a buffered writer for WebSocket connection: all data written to it is sent to the client in
+// message(s) of <msgType> (websocket.BinaryMessage when 0).
+//
+// gorilla/websocket does not support concurrent writes, so don't write to <conn> while Streamer is attached to it.
+// Control messages (see websocket.Conn.WriteControl) are safe to send at any time.
+func NewWSWriter(conn *websocket.Conn, msgType int) *bufio.Writer {
+	return NewWSWriterWithDeadline(conn, msgType, 0)
+}
+
+// NewWSWriterWithDeadline is NewWSWriter() with write deadline: each message must be sent within <writeTimeout>, so a
+// dead peer does not block the stream forever. 0 means 'no write deadline'.
+func NewWSWriterWithDeadline(conn *websocket.Conn, msgType int, writeTimeout time.Duration) *bufio.Writer {
+	if msgType == 0 {
+		msgType = websocket.BinaryMessage
+	}
+
+	return bufio.NewWriter(&webSocketWriter{conn: conn, msgType: msgType, writeTimeout: writeTimeout})
+}
+
+// WebSocketOptions configures StreamWebSocket().
+type WebSocketOptions struct {
+	InitialOffset int64
+	Timeout       time.Duration // see Streamer.StreamTo()
+
+	// WriteTimeout limits sending of each message, so a dead peer does not block the stream forever. Timeout is used
+	// when it is 0 (no write deadline when both are 0).
+	WriteTimeout time.Duration
+
+	// MessageType of messages with file data, websocket.TextMessage by default.
+	MessageType int
+
+	// CheckOrigin is passed to websocket.Upgrader. Nil means 'Origin host must be equal to the Host header'.
+	CheckOrigin func(r *http.Request) bool
+
+	// Trailer makes close message reason StreamSummary JSON instead of the error text.
+	Trailer bool
+
+	// Handshake makes the server accept HandshakeSubprotocol. Clients requesting it send their Hello as the first
+	// message and receive the agreed one (Agreement.Hello()) before file data. Other clients get the raw stream.
+	Handshake bool
+
+	// HeartbeatInterval offered in the handshake. Agreed heartbeats are sent as WebSocket ping messages.
+	HeartbeatInterval time.Duration
+}
+
+// HandshakeSubprotocol is the WebSocket subprotocol (Sec-WebSocket-Protocol) of streams that start with handshake.
+const HandshakeSubprotocol = "file-streamer"
+
+// Time the client has to send its Hello after the upgrade.
+const handshakeTimeout = 10 * time.Second
+
+// StreamWebSocket upgrades HTTP connection to WebSocket and streams file data there, a message per portion of data.
+// Errors that happen before the upgrade (file can't be opened, Streamer is not running) are sent as regular HTTP
+// responses.
+//
+// Streaming stops when the client closes the connection, the file is removed or options.Timeout expires. The
+// connection is closed with a close message then, its reason is the error text (or StreamSummary JSON with
+// options.Trailer).
+func StreamWebSocket(w http.ResponseWriter, req *http.Request, filePath string, streamer *Streamer, options WebSocketOptions) error {
+	if !streamer.IsRunning() {
+		http.Error(w, "Streaming service is not running", http.StatusServiceUnavailable)
+		return ErrNotRunning
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		http.Error(w, "Can't open file for streaming: "+err.Error(), http.StatusNotFound)
+		return err
+	}
+	defer file.Close()
+
+	if _, err = file.Seek(options.InitialOffset, 0); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	upgrader := websocket.Upgrader{CheckOrigin: options.CheckOrigin}
+	if options.Handshake {
+		upgrader.Subprotocols = []string{HandshakeSubprotocol}
+	}
+	conn, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		return err // Upgrade() has already replied to the client
+	}
+	defer conn.Close()
+
+	msgType := options.MessageType
+	if msgType == 0 {
+		msgType = websocket.TextMessage
+	}
+
+	writeTimeout := options.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = options.Timeout
+	}
+
+	agreement := RawAgreement
+	if conn.Subprotocol() == HandshakeSubprotocol {
+		if agreement, err = webSocketHandshake(conn, ServerHello(options.HeartbeatInterval), writeTimeout); err != nil {
+			closeMessage := websocket.FormatCloseMessage(websocket.CloseProtocolError, err.Error())
+			_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+			return err
+		}
+	}
+
+	if agreement.Framing == FramingJSON {
+		msgType = websocket.TextMessage
+	}
+
+	listener := NewListener(file, NewWSWriterWithDeadline(conn, msgType, writeTimeout))
+	listener.SetAuditInfo(req.RemoteAddr, "")
+	if agreement.Framing == FramingJSON {
+		listener.SetEncoder(JSONEncoder)
+	}
+	if agreement.HeartbeatInterval != 0 {
+		listener.SetHeartbeat(agreement.HeartbeatInterval)
+		listener.SetEventHandler(func(event StreamEvent) {
+			if event.Type == EventHeartbeat {
+				_ = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+			}
+		})
+	}
+
+	// Reading starts after the handshake: gorilla/websocket does not support concurrent reads
+	go closeOnWebSocketClose(conn, listener)
+
+	err = streamer.StreamTo(listener, options.Timeout)
+	if err == errClientDisconnected {
+		return nil // regular end of stream
+	}
+
+	code, reason := websocket.CloseNormalClosure, ""
+	if err != nil {
+		code, reason = websocket.CloseInternalServerErr, err.Error()
+	}
+	if options.Trailer {
+		reason = summaryCloseReason(listener)
+	}
+	closeMessage := websocket.FormatCloseMessage(code, reason)
+	_ = conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(time.Second))
+
+	return err
+}
+
+// webSocketHandshake reads client's Hello, negotiates with <server> and replies with the agreed Hello.
+func webSocketHandshake(conn *websocket.Conn, server Hello, writeTimeout time.Duration) (Agreement, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(handshakeTimeout)); err != nil {
+		return Agreement{}, err
+	}
+
+	var client Hello
+	if err := conn.ReadJSON(&client); err != nil {
+		return Agreement{}, err
+	}
+	if client.Type != HandshakeType {
+		return Agreement{}, ErrIncompatibleProtocol
+	}
+
+	agreement, err := Negotiate(server, client)
+	if err != nil {
+		return Agreement{}, err
+	}
+
+	if err = conn.SetReadDeadline(time.Time{}); err != nil {
+		return Agreement{}, err
+	}
+	if writeTimeout != 0 {
+		if err = conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+			return Agreement{}, err
+		}
+	}
+
+	return agreement, conn.WriteJSON(agreement.Hello())
+}
+
+// closeOnWebSocketClose reads (and drops) client messages, closing <listener> when the client closes the connection.
+// Ping messages are answered by gorilla/websocket default ping handler.
+func closeOnWebSocketClose(conn *websocket.Conn, listener *Listener) {
+	for {
+		if _, _, err := conn.NextReader(); err != nil {
+			listener.closeWithError(errClientDisconnected)
+			return
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package file_streamer
+
+import (
+	"github.com/gorilla/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The client never reads messages: writes must fail after the deadline instead of blocking the stream forever.
+func TestWSWriterDeadline(t *testing.T) {
+	type writeResult struct {
+		n   int
+		err error
+	}
+	results := make(chan writeResult, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		writer := &webSocketWriter{conn: conn, msgType: websocket.BinaryMessage, writeTimeout: 50 * time.Millisecond}
+		message := make([]byte, 1024*1024)
+		for {
+			if n, err := writer.Write(message); err != nil {
+				results <- writeResult{n, err}
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	select {
+	case result := <-results:
+		if result.n != 0 {
+			t.Errorf("failed write returned %d written bytes, want 0", result.n)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("write to the dead client did not fail")
+	}
+}
+
+func TestStreamWebSocketHandshake(t *testing.T) {
+	s := startTestStreamer(t)
+	fileName := createTestFile(t, "hello\n")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = StreamWebSocket(w, r, fileName, s, WebSocketOptions{Handshake: true, Timeout: 5 * time.Second})
+	}))
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	t.Run("raw client", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "hello\n" {
+			t.Errorf("got %q, want raw file data", data)
+		}
+	})
+
+	t.Run("json framing", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{HandshakeSubprotocol}}
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if err = conn.WriteJSON(Hello{Version: ProtocolVersion, Framing: []string{FramingJSON}}); err != nil {
+			t.Fatal(err)
+		}
+
+		var reply Hello
+		if err = conn.ReadJSON(&reply); err != nil {
+			t.Fatal(err)
+		}
+		if reply.Version != ProtocolVersion || len(reply.Framing) != 1 || reply.Framing[0] != FramingJSON {
+			t.Fatalf("agreed on %+v", reply)
+		}
+
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != `"hello\n"`+"\n" {
+			t.Errorf("got %q, want JSON-encoded file data", data)
+		}
+	})
+
+	t.Run("incompatible client", func(t *testing.T) {
+		dialer := websocket.Dialer{Subprotocols: []string{HandshakeSubprotocol}}
+		conn, _, err := dialer.Dial(url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		if err = conn.WriteJSON(Hello{Version: ProtocolVersion + 2, MinVersion: ProtocolVersion + 1}); err != nil {
+			t.Fatal(err)
+		}
+
+		_, _, err = conn.ReadMessage()
+		if !websocket.IsCloseError(err, websocket.CloseProtocolError) {
+			t.Errorf("got %v, want protocol error close", err)
+		}
+	})
+}